@@ -3,20 +3,14 @@
 
 package ao
 
-// #cgo pkg-config: ao
-//
-// #include <stdlib.h>
-// #include <ao/ao.h>
-import "C"
 import (
-	"errors"
-	"sync/atomic"
-	"unsafe"
+	"fmt"
+	"strings"
 )
 
 // libInitialized is an atomically updated flag which determines if the
-// ao subsystems have been initialized. It is set by Init() and
-// unset in Shutdown()
+// ao subsystems have been initialized. It is set when the first reference
+// to the library is acquired and unset when the last one is released.
 var libInitialized uint32
 
 // Init must be called before anything else in this package and
@@ -33,12 +27,28 @@ var libInitialized uint32
 // is initialized in a non-main thread that later exits, these undeleted
 // keys will cause a segmentation fault.
 //
+// To this end, the package makes all calls into libao from a single OS
+// thread, regardless of the goroutine calling Init or using a Device.
+// Programs which must initialize libao on the actual main thread should
+// hand it over with Main().
+//
+// Options set with SetGlobalOption() and SetVerbosity() are passed to libao
+// before it is initialized.
+//
 // If you want to reload the configuration files without restarting your
 // program, first call Shutdown(), then call Init() again. Multiple successive
 // calls to either Init() or Shutdown() will be silently ignored.
+//
+// Init holds a single reference to the library, shared with Shutdown().
+// Components which need the library independently of each other should
+// use Open() instead.
 func Init() {
-	if atomic.CompareAndSwapUint32(&libInitialized, 0, 1) {
-		C.ao_initialize()
+	libLock.Lock()
+	defer libLock.Unlock()
+
+	if !libLegacy {
+		libLegacy = true
+		acquire()
 	}
 }
 
@@ -48,12 +58,105 @@ func Init() {
 // If you want to reload the configuration files without restarting your
 // program, first call Shutdown(), then call Init() again. Multiple successive
 // calls to either Init() or Shutdown() will be silently ignored.
+//
+// Shutdown releases the reference held by Init(). The library is only
+// shut down if no references obtained through Open() remain. Devices
+// which are still open at that point are closed.
 func Shutdown() {
-	if atomic.CompareAndSwapUint32(&libInitialized, 1, 0) {
-		C.ao_shutdown()
+	libLock.Lock()
+	defer libLock.Unlock()
+
+	if libLegacy {
+		libLegacy = false
+		release()
 	}
 }
 
+// Verbosity defines how much diagnostic output libao produces.
+type Verbosity int
+
+// Known verbosity levels.
+const (
+	VerbosityNormal  Verbosity = iota // Errors and warnings only.
+	VerbosityQuiet                    // No output at all.
+	VerbosityVerbose                  // Informational messages as well.
+	VerbosityDebug                    // Debugging messages as well.
+)
+
+// option is a single key/value pair.
+type option struct {
+	key, value string
+}
+
+// globalOptions holds the options set with SetGlobalOption().
+// It is guarded by libLock.
+var globalOptions []option
+
+// verbosityKeys maps verbosity levels to their global option keys.
+var verbosityKeys = map[Verbosity]string{
+	VerbosityQuiet:   "quiet",
+	VerbosityVerbose: "verbose",
+	VerbosityDebug:   "debug",
+}
+
+// SetGlobalOption sets an option which applies to all drivers. It replaces
+// an earlier value for the same key. Global options are passed to libao
+// whenever it is initialized; they are overridden by the options passed
+// to OpenLive() and OpenFile().
+//
+// Returns ErrInitialized if the library is currently initialized.
+func SetGlobalOption(key, value string) error {
+	libLock.Lock()
+	defer libLock.Unlock()
+
+	if initialized() {
+		return ErrInitialized
+	}
+
+	setGlobalOption(key, value)
+	return nil
+}
+
+// setGlobalOption replaces or adds the given option. libLock must be held.
+func setGlobalOption(key, value string) {
+	for i := range globalOptions {
+		if globalOptions[i].key == key {
+			globalOptions[i].value = value
+			return
+		}
+	}
+
+	globalOptions = append(globalOptions, option{key, value})
+}
+
+// SetVerbosity sets the global "quiet", "verbose" or "debug" option,
+// and clears the other two. Route the resulting output elsewhere with
+// SetLogger().
+//
+// Returns ErrInitialized if the library is currently initialized.
+func SetVerbosity(v Verbosity) error {
+	libLock.Lock()
+	defer libLock.Unlock()
+
+	if initialized() {
+		return ErrInitialized
+	}
+
+	opts := globalOptions[:0]
+	for _, opt := range globalOptions {
+		if opt.key != "quiet" && opt.key != "verbose" && opt.key != "debug" {
+			opts = append(opts, opt)
+		}
+	}
+	globalOptions = opts
+
+	if key, ok := verbosityKeys[v]; ok {
+		setGlobalOption(key, "")
+	}
+
+	return nil
+}
+
 // DefaultDriver returns the ID number of the default live output driver.
 // If the configuration files specify a default driver, its ID is returned.
 // Otherwise the library tries to pick a live output driver that will work
@@ -63,11 +166,19 @@ func Shutdown() {
 // for testing porpuses.
 //
 // If no audio hardware is available, it is in use, or is not in the "standard"
-// configuration, this returns -1.
+// configuration, this returns -1 and ErrNoDriver.
+// If the library is not initialized, it returns -1 and ErrNotInitialized.
 func DefaultDriver() (id int, err error) {
-	id = int(C.ao_default_driver_id())
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return -1, ErrNotInitialized
+	}
+
+	id = defaultDriver()
 	if id == -1 {
-		err = errors.New("No default driver found")
+		err = ErrNoDriver
 	}
 	return
 }
@@ -76,14 +187,77 @@ func DefaultDriver() (id int, err error) {
 // Refer to https://xiph.org/ao/doc/drivers.html for a list of supported
 // driver names.
 //
-// Returns -1 if no matching driver was found.
+// Returns -1 and an error wrapping ErrNoDriver if no matching driver was found,
+// or ErrNotInitialized if the library is not initialized.
 func DriverByName(name string) (id int, err error) {
-	cname := C.CString(name)
-	v := C.ao_driver_id(cname)
-	C.free(unsafe.Pointer(cname))
-	id = int(v)
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return -1, ErrNotInitialized
+	}
+
+	id = driverID(name)
 	if id == -1 {
-		err = errors.New("No matching driver found")
+		err = fmt.Errorf("driver %q: %w", name, ErrNoDriver)
 	}
 	return
 }
+
+// DriverForExtension returns the id of the file output driver which
+// normally writes files with the given extension; e.g.: ".wav" or "au".
+// The leading dot is optional and case is ignored. Registered Go drivers
+// take precedence over those of libao.
+//
+// Returns -1 and an error wrapping ErrUnknownExtension if no driver uses
+// the extension, or ErrNotFile if it belongs to a live output driver.
+func DriverForExtension(ext string) (int, error) {
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return -1, ErrNotInitialized
+	}
+
+	ext = strings.TrimPrefix(ext, ".")
+	if len(ext) == 0 {
+		return -1, fmt.Errorf("extension %q: %w", ext, ErrUnknownExtension)
+	}
+
+	for _, info := range append(goDriverInfoList(), libDriverInfoList()...) {
+		if !strings.EqualFold(info.Extension, ext) {
+			continue
+		}
+
+		if info.Type != DriverFile {
+			return -1, fmt.Errorf("extension %q: %w", ext, ErrNotFile)
+		}
+
+		return info.ID, nil
+	}
+
+	return -1, fmt.Errorf("extension %q: %w", ext, ErrUnknownExtension)
+}
+
+// openError creates an OpenError for a failed attempt to open a device
+// with the given driver and, optionally, filename.
+func openError(driver int, filename string, err error) error {
+	if err == nil {
+		err = ErrFail
+	}
+
+	return &OpenError{
+		Driver:   shortName(driver),
+		Filename: filename,
+		Err:      err,
+	}
+}
+
+// shortName returns the short name of the given driver,
+// or an empty string if it does not exist.
+func shortName(driver int) string {
+	if info, ok := driverInfo(driver); ok {
+		return info.ShortName
+	}
+	return ""
+}
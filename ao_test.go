@@ -4,8 +4,12 @@
 package ao
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 // null is a special driver for testing.
@@ -30,8 +34,8 @@ func Test(t *testing.T) {
 	Init()
 	defer Shutdown()
 
-	driver := DriverByName(driverName)
-	if driver < 0 {
+	driver, err := DriverByName(driverName)
+	if err != nil {
 		t.Errorf("No driver named %q", driverName)
 		return
 	}
@@ -58,4 +62,194 @@ func Test(t *testing.T) {
 			return
 		}
 	}
+
+	if dev.Driver() != driver || !dev.IsLive() || dev.Format() != *format {
+		t.Errorf("Device does not match open parameters")
+	}
+
+	if want := uint64(32 * len(buf)); dev.BytesWritten() != want {
+		t.Errorf("BytesWritten: want %d, have %d", want, dev.BytesWritten())
+	}
+
+	if want := uint64(32 * len(buf) / 4); dev.FramesWritten() != want {
+		t.Errorf("FramesWritten: want %d, have %d", want, dev.FramesWritten())
+	}
+}
+
+func TestDrivers(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	info, err := DriverInfoByID(driver)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if info.ShortName != driverName || info.Type != DriverLive {
+		t.Errorf("Unexpected driver info: %+v", info)
+	}
+
+	for _, di := range Drivers() {
+		if di.ID == driver {
+			return
+		}
+	}
+
+	t.Errorf("Driver %q not listed by Drivers()", driverName)
+}
+
+func TestOpenErrors(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	live, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	wav, err := DriverByName("wav")
+	if err != nil {
+		t.Errorf("No driver named %q", "wav")
+		return
+	}
+
+	if _, err = OpenFile(live, "", true, format, nil); !errors.Is(err, ErrNotFile) {
+		t.Errorf("OpenFile on live driver: want ErrNotFile, have %v", err)
+	}
+
+	if _, err = OpenLive(wav, format, nil); !errors.Is(err, ErrNotLive) {
+		t.Errorf("OpenLive on file driver: want ErrNotLive, have %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.wav")
+
+	dev, err := OpenFile(wav, filename, false, format, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dev.Close()
+
+	_, err = OpenFile(wav, filename, false, format, nil)
+	if !errors.Is(err, ErrFileExists) {
+		t.Errorf("OpenFile on existing file: want ErrFileExists, have %v", err)
+	}
+}
+
+func TestPartialFrames(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	dev, err := OpenLive(driver, format, options)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// 16-bit stereo has 4-byte frames.
+	for _, size := range []int{0, 1, 2, 5, 3, 7, 6} {
+		n, err := dev.Write(make([]byte, size))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if n != size {
+			t.Errorf("Write(%d): have %d", size, n)
+		}
+	}
+
+	if dev.BytesWritten() != 24 {
+		t.Errorf("BytesWritten: want 24, have %d", dev.BytesWritten())
+	}
+
+	dev.Write(make([]byte, 3))
+
+	if err = dev.Close(); err != ErrPartialFrame {
+		t.Errorf("Close: want ErrPartialFrame, have %v", err)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	dev, err := OpenLive(driver, format, options)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer dev.Close()
+
+	buf := make([]byte, 4*dev.chunkSize()+2)
+
+	n, err := dev.WriteContext(context.Background(), buf)
+	if n != len(buf) || err != nil {
+		t.Errorf("WriteContext: want %d, nil; have %d, %v", len(buf), n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	n, err = dev.WriteContext(ctx, buf)
+	if n != 0 || err != context.Canceled {
+		t.Errorf("WriteContext: want 0, context.Canceled; have %d, %v", n, err)
+	}
+}
+
+func TestWriteContextLocked(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := OpenLive(driver, format, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dev.Close()
+
+	// Pretend another write is in progress.
+	dev.lockWrite(context.Background())
+	defer dev.unlockWrite()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	n, err := dev.WriteContext(ctx, make([]byte, 4))
+
+	if n != 0 || err != context.DeadlineExceeded {
+		t.Errorf("WriteContext: want 0, context.DeadlineExceeded; have %d, %v", n, err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("WriteContext returned after %v", d)
+	}
 }
@@ -0,0 +1,342 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+// Package aotest provides helpers for testing code which generates audio.
+//
+// Audio is passed around as interleaved PCM data along with its
+// ao.SampleFormat, as recorded by an ao.Capture driver, for example.
+// Comparisons take place on samples normalized to the range [-1, 1],
+// so they can allow for small numerical differences.
+//
+// Golden files are stored as WAV files in the testdata directory.
+// Run the tests with the -aotest.update flag to (re)create them:
+//
+//	go test -run TestSoundEffects -aotest.update
+//
+// The flag name is qualified, so it does not clash with an -update flag
+// defined by the package under test.
+package aotest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jteeuwen/ao"
+)
+
+// update makes Golden() write the received audio to the golden files,
+// instead of comparing against them.
+var update = flag.Bool("aotest.update", false, "update aotest golden files")
+
+// GoldenDir is the directory golden files are stored in.
+var GoldenDir = "testdata"
+
+// maxDiffLines limits the number of mismatching samples listed by Diff.
+const maxDiffLines = 10
+
+// Samples decodes PCM data in the given format into interleaved
+// samples in the range [-1, 1]. A trailing partial frame is ignored.
+func Samples(data []byte, sf *ao.SampleFormat) []float64 {
+	s := ao.NewPCMStream(bytes.NewReader(data), sf)
+	buf := make([]float32, 4096*sf.Channels)
+
+	var out []float64
+	for {
+		n, err := s.ReadSamples(buf)
+		for _, v := range buf[:n] {
+			out = append(out, float64(v))
+		}
+
+		if err != nil || n == 0 {
+			return out
+		}
+	}
+}
+
+// Mono averages the channels of the interleaved samples.
+func Mono(samples []float64, channels int) []float64 {
+	if channels <= 1 {
+		return samples
+	}
+
+	out := make([]float64, len(samples)/channels)
+	for i := range out {
+		var sum float64
+		for _, v := range samples[i*channels : i*channels+channels] {
+			sum += v
+		}
+		out[i] = sum / float64(channels)
+	}
+
+	return out
+}
+
+// Diff compares interleaved samples and returns a readable report of
+// the differences. Samples which differ by no more than tolerance are
+// considered equal. Returns an empty string if there are no differences.
+func Diff(have, want []float64, channels int, tolerance float64) string {
+	if channels < 1 {
+		channels = 1
+	}
+
+	var sb strings.Builder
+	var count int
+	var worst float64
+
+	n := len(have)
+	if len(want) < n {
+		n = len(want)
+	}
+
+	for i := 0; i < n; i++ {
+		d := math.Abs(have[i] - want[i])
+		if d <= tolerance {
+			continue
+		}
+
+		if count < maxDiffLines {
+			fmt.Fprintf(&sb, "  frame %d, channel %d: have %+.6f, want %+.6f (off by %.6f)\n",
+				i/channels, i%channels, have[i], want[i], d)
+		}
+
+		count++
+		worst = math.Max(worst, d)
+	}
+
+	if count > maxDiffLines {
+		fmt.Fprintf(&sb, "  ... and %d more\n", count-maxDiffLines)
+	}
+
+	if count > 0 {
+		fmt.Fprintf(&sb, "%d of %d samples differ by more than %g; largest difference is %g\n",
+			count, n, tolerance, worst)
+	}
+
+	if len(have) != len(want) {
+		fmt.Fprintf(&sb, "have %d frames, want %d\n", len(have)/channels, len(want)/channels)
+	}
+
+	return sb.String()
+}
+
+// EqualPCM reports an error if the given PCM buffers hold samples
+// which differ by more than tolerance.
+func EqualPCM(t testing.TB, have, want []byte, sf *ao.SampleFormat, tolerance float64) {
+	t.Helper()
+
+	diff := Diff(Samples(have, sf), Samples(want, sf), sf.Channels, tolerance)
+	if len(diff) > 0 {
+		t.Errorf("PCM data differs:\n%s", diff)
+	}
+}
+
+// Golden compares the PCM data against the golden file testdata/<name>.wav.
+// If the -aotest.update flag is set, the golden file is written instead.
+// The golden file's sample format must match sf. Its channels are compared
+// in the order of sf's matrix; the file does not record speaker positions.
+func Golden(t testing.TB, name string, have []byte, sf *ao.SampleFormat, tolerance float64) {
+	t.Helper()
+
+	path := filepath.Join(GoldenDir, name+".wav")
+
+	if *update {
+		if err := writeGolden(path, have, sf); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("golden file: %v; run with -aotest.update to create it", err)
+	}
+
+	defer fd.Close()
+
+	want, wsf, err := ReadWAV(fd)
+	if err != nil {
+		t.Fatalf("golden file %s: %v", path, err)
+	}
+
+	if wsf.Bits != sf.Bits || wsf.Rate != sf.Rate || wsf.Channels != sf.Channels {
+		t.Fatalf("golden file %s: format is %v, want %v", path, &wsf, sf)
+	}
+
+	diff := Diff(Samples(have, sf), Samples(want, &wsf), sf.Channels, tolerance)
+	if len(diff) > 0 {
+		t.Errorf("audio differs from golden file %s:\n%s", path, diff)
+	}
+}
+
+// writeGolden writes the PCM data to the given WAV file. The channels are
+// stored in the order given, rather than the WAV speaker order, so the file
+// compares equal to the data when it is read back.
+func writeGolden(path string, data []byte, sf *ao.SampleFormat) error {
+	plain := *sf
+	plain.Matrix = ""
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	enc, err := ao.NewWAVEncoder(fd, &plain)
+	if err == nil {
+		_, err = enc.Write(data)
+	}
+
+	if err == nil {
+		err = enc.Close()
+	}
+
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// DominantFrequency returns the frequency in Hz with the most energy
+// in the given mono samples.
+func DominantFrequency(samples []float64, rate int) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	spectrum := fft(samples)
+
+	var peak int
+	var max float64
+	for i := 1; i < len(spectrum)/2; i++ {
+		if m := cmplx.Abs(spectrum[i]); m > max {
+			peak, max = i, m
+		}
+	}
+
+	return float64(peak) * float64(rate) / float64(len(spectrum))
+}
+
+// RMS returns the root mean square level of the given samples.
+func RMS(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range samples {
+		sum += v * v
+	}
+
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// Span defines a section of audio.
+type Span struct {
+	Start, End time.Duration
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("[%v, %v)", s.Start, s.End)
+}
+
+// SilentSpans returns the sections of audio, lasting at least minLength,
+// in which no sample exceeds the given threshold.
+func SilentSpans(data []byte, sf *ao.SampleFormat, threshold float64, minLength time.Duration) []Span {
+	samples := Samples(data, sf)
+	frames := len(samples) / sf.Channels
+	at := func(frame int) time.Duration {
+		return time.Duration(int64(frame) * int64(time.Second) / int64(sf.Rate))
+	}
+
+	var spans []Span
+	start := -1
+
+	for f := 0; f <= frames; f++ {
+		silent := f < frames
+		if silent {
+			for _, v := range samples[f*sf.Channels : f*sf.Channels+sf.Channels] {
+				silent = silent && math.Abs(v) <= threshold
+			}
+		}
+
+		switch {
+		case silent && start < 0:
+			start = f
+		case !silent && start >= 0:
+			if span := (Span{at(start), at(f)}); span.End-span.Start >= minLength {
+				spans = append(spans, span)
+			}
+			start = -1
+		}
+	}
+
+	return spans
+}
+
+// AssertFrequency reports an error if the dominant frequency of the audio
+// differs from want by more than tolerance Hz.
+func AssertFrequency(t testing.TB, data []byte, sf *ao.SampleFormat, want, tolerance float64) {
+	t.Helper()
+
+	have := DominantFrequency(Mono(Samples(data, sf), sf.Channels), sf.Rate)
+	if math.Abs(have-want) > tolerance {
+		t.Errorf("dominant frequency is %.2f Hz, want %.2f ±%.2f Hz", have, want, tolerance)
+	}
+}
+
+// AssertRMS reports an error if the RMS level of the audio differs from
+// want by more than tolerance.
+func AssertRMS(t testing.TB, data []byte, sf *ao.SampleFormat, want, tolerance float64) {
+	t.Helper()
+
+	have := RMS(Samples(data, sf))
+	if math.Abs(have-want) > tolerance {
+		t.Errorf("RMS level is %.4f, want %.4f ±%.4f", have, want, tolerance)
+	}
+}
+
+// AssertSilence reports an error if any sample in the given span
+// exceeds the threshold.
+func AssertSilence(t testing.TB, data []byte, sf *ao.SampleFormat, span Span, threshold float64) {
+	t.Helper()
+
+	size := sf.FrameSize()
+	from := sf.BytesFor(span.Start)
+	to := sf.BytesFor(span.End)
+
+	if from > len(data) || to > len(data) || from > to {
+		t.Errorf("span %v is out of range; audio lasts %v", span, sf.DurationOf(len(data)))
+		return
+	}
+
+	for f, v := range Samples(data[from:to], sf) {
+		if math.Abs(v) > threshold {
+			at := span.Start + sf.DurationOf((f/sf.Channels)*size)
+			t.Errorf("span %v is not silent: sample at %v is %+.4f", span, at, v)
+			return
+		}
+	}
+}
+
+// AssertDuration reports an error if the playback duration of the audio
+// differs from want by more than tolerance.
+func AssertDuration(t testing.TB, data []byte, sf *ao.SampleFormat, want, tolerance time.Duration) {
+	t.Helper()
+
+	have := sf.DurationOf(len(data))
+	if d := have - want; d > tolerance || -d > tolerance {
+		t.Errorf("duration is %v, want %v ±%v", have, want, tolerance)
+	}
+}
@@ -0,0 +1,171 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package aotest
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jteeuwen/ao"
+)
+
+// Importing packages commonly define an -update flag of their own.
+// This panics if aotest defines one as well.
+var _ = flag.Bool("update", false, "update golden files of the package under test")
+
+var format = ao.SampleFormat{
+	Bits:      16,
+	Rate:      8000,
+	Channels:  1,
+	ByteOrder: ao.EndianLittle,
+}
+
+// tone generates a sine wave, followed by the given amount of silence.
+func tone(freq, amp float64, length, silence time.Duration) []byte {
+	n := format.BytesFor(length) / 2
+	m := format.BytesFor(silence) / 2
+	b := make([]byte, 2*(n+m))
+
+	for i := 0; i < n; i++ {
+		v := int16(amp * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(format.Rate)))
+		b[2*i] = byte(v)
+		b[2*i+1] = byte(v >> 8)
+	}
+
+	return b
+}
+
+func TestAssertions(t *testing.T) {
+	data := tone(440, 0.5, 500*time.Millisecond, 250*time.Millisecond)
+	sound := data[:format.BytesFor(500*time.Millisecond)]
+
+	AssertFrequency(t, sound, &format, 440, 5)
+	AssertRMS(t, sound, &format, 0.5/math.Sqrt2, 0.01)
+	AssertDuration(t, data, &format, 750*time.Millisecond, time.Millisecond)
+	AssertSilence(t, data, &format, Span{500 * time.Millisecond, 750 * time.Millisecond}, 0.001)
+
+	spans := SilentSpans(data, &format, 0.001, 100*time.Millisecond)
+	if len(spans) != 1 || spans[0].End != 750*time.Millisecond ||
+		spans[0].Start < 499*time.Millisecond || spans[0].Start > 500*time.Millisecond {
+		t.Fatalf("silent spans: have %v, want [[500ms, 750ms)]", spans)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	data := tone(440, 0.5, 250*time.Millisecond, 0)
+
+	for name, fn := range map[string]func(testing.TB){
+		"frequency": func(t testing.TB) { AssertFrequency(t, data, &format, 880, 5) },
+		"rms":       func(t testing.TB) { AssertRMS(t, data, &format, 0.1, 0.01) },
+		"duration":  func(t testing.TB) { AssertDuration(t, data, &format, time.Second, 0) },
+		"silence":   func(t testing.TB) { AssertSilence(t, data, &format, Span{0, 100 * time.Millisecond}, 0.01) },
+	} {
+		ft := &fakeT{TB: t}
+		fn(ft)
+
+		if !ft.failed {
+			t.Errorf("%s: assertion did not fail", name)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	have := []float64{0, 0.5, 1, 1, 0.25, 0}
+	want := []float64{0, 0.5, 1, 0.5, 0.25}
+
+	if diff := Diff(want, want, 2, 0); len(diff) > 0 {
+		t.Fatalf("identical samples: unexpected diff:\n%s", diff)
+	}
+
+	diff := Diff(have, want, 2, 0.01)
+	for _, s := range []string{
+		"frame 1, channel 1: have +1.000000, want +0.500000",
+		"1 of 5 samples differ",
+		"have 3 frames, want 2",
+	} {
+		if !strings.Contains(diff, s) {
+			t.Errorf("diff does not contain %q:\n%s", s, diff)
+		}
+	}
+}
+
+func TestEqualPCM(t *testing.T) {
+	a := tone(1000, 0.5, 10*time.Millisecond, 0)
+	b := append([]byte(nil), a...)
+	b[10]++ // Off by one LSB.
+
+	EqualPCM(t, a, b, &format, 1.0/16384)
+
+	ft := &fakeT{TB: t}
+	EqualPCM(ft, a, b, &format, 0)
+
+	if !ft.failed {
+		t.Fatal("EqualPCM did not fail on differing data")
+	}
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "tone", tone(1000, 0.5, 20*time.Millisecond, 0), &format, 0)
+}
+
+func TestGoldenMultichannel(t *testing.T) {
+	defer func(dir string, up bool) { GoldenDir, *update = dir, up }(GoldenDir, *update)
+	GoldenDir = t.TempDir()
+
+	// Matrix51 is not in WAV speaker order, so the encoder would reorder it.
+	sf := ao.SampleFormat{Bits: 16, Rate: 8000, Channels: 6, ByteOrder: ao.EndianLittle, Matrix: ao.Matrix51}
+	data := make([]byte, 10*sf.FrameSize())
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	*update = true
+	Golden(t, "surround", data, &sf, 0)
+
+	*update = false
+	Golden(t, "surround", data, &sf, 0)
+}
+
+func TestReadWAV(t *testing.T) {
+	sf := ao.SampleFormat{Bits: 8, Rate: 22050, Channels: 2, ByteOrder: ao.EndianNative}
+	want := []byte{0x00, 0x7f, 0x80, 0xff, 0x10, 0xf0}
+
+	var buf bytes.Buffer
+	enc, err := ao.NewWAVEncoder(&buf, &sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc.Write(want)
+	if err = enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	have, hsf, err := ReadWAV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hsf.Bits != 8 || hsf.Rate != 22050 || hsf.Channels != 2 {
+		t.Fatalf("format: have %v, want %v", &hsf, &sf)
+	}
+
+	if !bytes.Equal(have, want) {
+		t.Fatalf("data: have %x, want %x", have, want)
+	}
+}
+
+// fakeT records failures instead of reporting them.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper()                           {}
+func (t *fakeT) Errorf(format string, args ...any) { t.failed = true }
+func (t *fakeT) Fatalf(format string, args ...any) { t.failed = true }
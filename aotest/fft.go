@@ -0,0 +1,55 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package aotest
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft returns the discrete Fourier transform of the given samples.
+// They are windowed with a Hann window and zero padded to the next
+// power of two.
+func fft(samples []float64) []complex128 {
+	n := 1
+	for n < len(samples) {
+		n <<= 1
+	}
+
+	x := make([]complex128, n)
+	for i, v := range samples {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(samples)-1))
+		x[i] = complex(v*w, 0)
+	}
+
+	// Bit reversal permutation.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := x[start+k+size/2] * w
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+
+	return x
+}
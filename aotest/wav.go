@@ -0,0 +1,113 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package aotest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jteeuwen/ao"
+)
+
+// WAV format tags.
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatExtensible = 0xfffe
+)
+
+// ReadWAV reads an uncompressed PCM WAV file. It returns the sample data
+// and its format. 8-bit samples are converted to signed samples, so the
+// data can be passed to an ao.Device as is. Channels are returned in the
+// order they are stored in; the channel mask of an extensible file is
+// ignored.
+func ReadWAV(r io.Reader) ([]byte, ao.SampleFormat, error) {
+	var sf ao.SampleFormat
+	le := binary.LittleEndian
+
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, sf, err
+	}
+
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, sf, errors.New("not a RIFF WAVE file")
+	}
+
+	var haveFormat bool
+
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				err = errors.New("missing data chunk")
+			}
+			return nil, sf, err
+		}
+
+		id := string(hdr[:4])
+		size := le.Uint32(hdr[4:])
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return nil, sf, fmt.Errorf("invalid fmt chunk size %d", size)
+			}
+
+			chunk := make([]byte, size+size&1)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, sf, err
+			}
+
+			tag := le.Uint16(chunk)
+			if tag == wavFormatExtensible && size >= 40 {
+				tag = le.Uint16(chunk[24:])
+			}
+
+			if tag != wavFormatPCM {
+				return nil, sf, fmt.Errorf("unsupported format tag %#04x", tag)
+			}
+
+			sf.Channels = int(le.Uint16(chunk[2:]))
+			sf.Rate = int(le.Uint32(chunk[4:]))
+			sf.Bits = int(le.Uint16(chunk[14:]))
+			sf.ByteOrder = ao.EndianLittle
+			haveFormat = true
+
+		case "data":
+			if !haveFormat {
+				return nil, sf, errors.New("data chunk precedes fmt chunk")
+			}
+
+			var data []byte
+			var err error
+
+			if size == 0xffffffff {
+				// Size unknown; written to a non-seekable file.
+				data, err = io.ReadAll(r)
+			} else {
+				data = make([]byte, size)
+				_, err = io.ReadFull(r, data)
+			}
+
+			if err != nil {
+				return nil, sf, err
+			}
+
+			if sf.Bits == 8 {
+				for i := range data {
+					data[i] ^= 0x80
+				}
+			}
+
+			return data, sf, nil
+
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size&1)); err != nil {
+				return nil, sf, err
+			}
+		}
+	}
+}
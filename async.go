@@ -0,0 +1,217 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import "sync"
+
+// AsyncPlayer plays audio on a Device from a dedicated goroutine.
+// Buffers are accepted through a bounded queue. Enqueue blocks while
+// the queue is full, which keeps producers from running too far ahead
+// of the playback position.
+//
+// Playback happens in small chunks, so Pause and Stop take effect
+// without waiting for the current buffer to finish.
+//
+// The player does not take ownership of the device; the caller must
+// still close it once the player has been stopped.
+type AsyncPlayer struct {
+	dev     *Device
+	queue   chan []byte
+	errs    chan error
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending int  // Number of buffers enqueued, but not yet played.
+	paused  bool // Is playback currently paused?
+	stopped bool // Has the player been stopped?
+}
+
+// NewAsyncPlayer creates a player for the given device and starts its
+// playback goroutine. The queue holds at most size buffers.
+func NewAsyncPlayer(dev *Device, size int) *AsyncPlayer {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &AsyncPlayer{
+		dev:   dev,
+		queue: make(chan []byte, size),
+		errs:  make(chan error, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	p.cond = sync.NewCond(&p.mu)
+	go p.run()
+	return p
+}
+
+// Errors returns a channel which receives playback errors. When an error
+// occurs, the player stops. The channel is closed once the playback
+// goroutine has exited.
+func (p *AsyncPlayer) Errors() <-chan error {
+	return p.errs
+}
+
+// Enqueue adds buf to the playback queue. It blocks while the queue is
+// full. The player keeps a reference to buf until it has been played,
+// so the caller must not modify it afterwards.
+//
+// Returns ErrStopped if the player has been stopped.
+func (p *AsyncPlayer) Enqueue(buf []byte) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return ErrStopped
+	}
+	p.pending++
+	p.mu.Unlock()
+
+	select {
+	case p.queue <- buf:
+	case <-p.stop:
+		p.finish()
+		return ErrStopped
+	}
+
+	// The player may have stopped while buf was being queued. If so,
+	// it will never be played. Remove a buffer from the queue in its
+	// place, in case the playback goroutine has already drained it.
+	p.mu.Lock()
+	stopped := p.stopped
+	p.mu.Unlock()
+
+	if stopped {
+		p.drainOne()
+		return ErrStopped
+	}
+
+	return nil
+}
+
+// Pause suspends playback at the next chunk boundary.
+func (p *AsyncPlayer) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+}
+
+// Resume continues playback after a call to Pause.
+func (p *AsyncPlayer) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// Stop halts playback at the next chunk boundary and discards all
+// queued buffers. It blocks until the playback goroutine has exited.
+// Multiple calls to Stop are silently ignored.
+func (p *AsyncPlayer) Stop() {
+	p.halt()
+	<-p.done
+}
+
+// Wait blocks until all enqueued buffers have been played, or the
+// player has been stopped.
+func (p *AsyncPlayer) Wait() {
+	p.mu.Lock()
+	for p.pending > 0 && !p.stopped {
+		p.cond.Wait()
+	}
+	p.mu.Unlock()
+}
+
+// halt marks the player as stopped and wakes up anyone waiting on it.
+func (p *AsyncPlayer) halt() {
+	p.once.Do(func() {
+		p.mu.Lock()
+		p.stopped = true
+		p.cond.Broadcast()
+		p.mu.Unlock()
+		close(p.stop)
+	})
+}
+
+// finish marks a single enqueued buffer as done.
+func (p *AsyncPlayer) finish() {
+	p.mu.Lock()
+	p.pending--
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// drain discards all buffers left in the queue once the player has stopped.
+func (p *AsyncPlayer) drain() {
+	for p.drainOne() {
+	}
+}
+
+// drainOne discards a single queued buffer, if there is one.
+func (p *AsyncPlayer) drainOne() bool {
+	select {
+	case <-p.queue:
+		p.finish()
+		return true
+	default:
+		return false
+	}
+}
+
+// run is the playback loop.
+func (p *AsyncPlayer) run() {
+	defer close(p.done)
+	defer close(p.errs)
+	defer p.drain()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case buf := <-p.queue:
+			err := p.play(buf)
+			p.finish()
+
+			if err != nil {
+				p.errs <- err
+				p.halt()
+				return
+			}
+		}
+	}
+}
+
+// play writes buf to the device in chunks, honouring pause and stop
+// requests between chunks.
+func (p *AsyncPlayer) play(buf []byte) error {
+	chunk := p.dev.chunkSize()
+
+	for len(buf) > 0 {
+		p.mu.Lock()
+		for p.paused && !p.stopped {
+			p.cond.Wait()
+		}
+		stopped := p.stopped
+		p.mu.Unlock()
+
+		if stopped {
+			return nil
+		}
+
+		n := chunk
+		if n > len(buf) {
+			n = len(buf)
+		}
+
+		if err := p.dev.Play(buf[:n]); err != nil {
+			return err
+		}
+
+		buf = buf[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAsyncPlayer(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	dev, err := OpenLive(driver, format, options)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer dev.Close()
+
+	p := NewAsyncPlayer(dev, 2)
+	p.Pause()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 8; i++ {
+			if err := p.Enqueue(make([]byte, 64*1024)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	p.Resume()
+	<-done
+	p.Wait()
+
+	if want := uint64(8 * 64 * 1024); dev.BytesWritten() != want {
+		t.Errorf("BytesWritten: want %d, have %d", want, dev.BytesWritten())
+	}
+
+	p.Stop()
+	p.Stop()
+
+	if err := p.Enqueue(nil); err != ErrStopped {
+		t.Errorf("Enqueue after Stop: want ErrStopped, have %v", err)
+	}
+
+	for err := range p.Errors() {
+		t.Error(err)
+	}
+}
+
+func TestAsyncPlayerStopRace(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 200; i++ {
+		dev, err := OpenLive(driver, format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every other device fails on its first write.
+		if i%2 == 0 {
+			dev.Close()
+		}
+
+		p := NewAsyncPlayer(dev, 4)
+
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 8; k++ {
+					if p.Enqueue(make([]byte, 64)) == ErrStopped {
+						return
+					}
+				}
+			}()
+		}
+
+		if i%2 == 1 {
+			p.Stop()
+		}
+
+		wg.Wait()
+		p.Stop()
+
+		if err := p.Enqueue(nil); err != ErrStopped {
+			t.Fatalf("Enqueue after Stop: want ErrStopped, have %v", err)
+		}
+
+		p.mu.Lock()
+		pending := p.pending
+		p.mu.Unlock()
+
+		if pending != 0 {
+			t.Fatalf("iteration %d: %d buffers left pending", i, pending)
+		}
+
+		dev.Close()
+	}
+}
@@ -0,0 +1,121 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Capture is a live Go driver which records all audio written to it.
+// It is meant for tests, which can assert on the exact samples that
+// would have reached the speaker. Make it available with RegisterDriver():
+//
+//	capture := new(ao.Capture)
+//	ao.RegisterDriver("capture", capture)
+//
+// Each device opened on the driver starts a new recording, replacing
+// the previous one.
+type Capture struct {
+	// Realtime makes writes block for the playback duration of the
+	// data written, as they would on a real sound device.
+	Realtime bool
+
+	mu     sync.Mutex
+	format SampleFormat
+	data   []byte
+}
+
+// Info describes the capture driver.
+func (c *Capture) Info() DriverInfo {
+	return DriverInfo{
+		Type:                DriverLive,
+		Name:                "Capture output",
+		Comment:             "Records all output in memory.",
+		PreferredByteFormat: EndianNative,
+	}
+}
+
+// Open starts a new recording in the given sample format.
+func (c *Capture) Open(w io.Writer, sf *SampleFormat, options map[string]string) (io.WriteCloser, error) {
+	c.mu.Lock()
+	c.format = *sf
+	c.data = nil
+	c.mu.Unlock()
+
+	return &captureWriter{c: c, start: time.Now()}, nil
+}
+
+// Format returns the sample format of the current recording.
+func (c *Capture) Format() SampleFormat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.format
+}
+
+// Bytes returns a copy of all data recorded so far.
+func (c *Capture) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.data...)
+}
+
+// Frames returns a copy of all data recorded so far, split into frames.
+// Each frame holds a single sample for every channel.
+func (c *Capture) Frames() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := c.format.FrameSize()
+	if size <= 0 {
+		return nil
+	}
+
+	data := append([]byte(nil), c.data...)
+	frames := make([][]byte, len(data)/size)
+
+	for i := range frames {
+		frames[i] = data[i*size : i*size+size : i*size+size]
+	}
+
+	return frames
+}
+
+// Reset discards the recorded data.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	c.data = nil
+	c.mu.Unlock()
+}
+
+// captureWriter records data for a single device.
+type captureWriter struct {
+	c       *Capture
+	start   time.Time
+	written int
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.c.mu.Lock()
+	w.c.data = append(w.c.data, p...)
+	sf := w.c.format
+	realtime := w.c.Realtime
+	w.c.mu.Unlock()
+
+	w.written += len(p)
+
+	// Sleep until the wall clock catches up with the audio clock.
+	if realtime {
+		if d := sf.DurationOf(w.written) - time.Since(w.start); d > 0 {
+			time.Sleep(d)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *captureWriter) Close() error {
+	return nil
+}
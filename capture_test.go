@@ -0,0 +1,80 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testCapture     = new(Capture)
+	registerCapture sync.Once
+)
+
+// openCapture opens a device on the capture driver.
+func openCapture(t *testing.T, sf *SampleFormat) *Device {
+	registerCapture.Do(func() { RegisterDriver("capture", testCapture) })
+
+	driver, err := DriverByName("capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := OpenLive(driver, sf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dev
+}
+
+func TestCapture(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	sf := SampleFormat{Bits: 16, Rate: 8000, Channels: 2, ByteOrder: EndianBig}
+	dev := openCapture(t, &sf)
+	defer dev.Close()
+
+	if err := dev.PlayInt16([]int16{1, -1, 0x1234, 0x5678}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testCapture.Format() != sf {
+		t.Errorf("Format: want %+v, have %+v", sf, testCapture.Format())
+	}
+
+	want := []byte{0x00, 0x01, 0xff, 0xff, 0x12, 0x34, 0x56, 0x78}
+	if have := testCapture.Bytes(); !bytes.Equal(have, want) {
+		t.Errorf("Bytes: want %x, have %x", want, have)
+	}
+
+	frames := testCapture.Frames()
+	if len(frames) != 2 || !bytes.Equal(frames[1], want[4:]) {
+		t.Errorf("Frames: have %x", frames)
+	}
+}
+
+func TestCaptureRealtime(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	testCapture.Realtime = true
+	defer func() { testCapture.Realtime = false }()
+
+	sf := SampleFormat{Bits: 8, Rate: 1000, Channels: 1}
+	dev := openCapture(t, &sf)
+	defer dev.Close()
+
+	start := time.Now()
+	dev.Play(make([]byte, 50))
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("50ms of audio played in %v", elapsed)
+	}
+}
@@ -0,0 +1,164 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Converter transforms interleaved PCM data from one sample format to
+// another. It changes bit depth and byte order; sample rate and channel
+// count must be identical in both formats. Use a Resampler or a Remapper
+// for those.
+//
+// libao treats 8-bit samples as signed. Set FromUnsigned8 or ToUnsigned8
+// to read or write unsigned 8-bit data instead, as used by WAV files.
+type Converter struct {
+	from, to      SampleFormat
+	Dither        bool // Apply TPDF dither when reducing the bit depth.
+	FromUnsigned8 bool // Source 8-bit samples are unsigned.
+	ToUnsigned8   bool // Target 8-bit samples are unsigned.
+	seed          uint64
+}
+
+// NewConverter creates a converter between the given sample formats.
+func NewConverter(from, to *SampleFormat) (*Converter, error) {
+	for _, sf := range []*SampleFormat{from, to} {
+		if err := sf.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if from.Rate != to.Rate || from.Channels != to.Channels {
+		return nil, fmt.Errorf("convert %v to %v: rate and channels must match: %w",
+			from, to, ErrBadFormat)
+	}
+
+	return &Converter{
+		from: *from,
+		to:   *to,
+		seed: 0x9e3779b97f4a7c15,
+	}, nil
+}
+
+// Append converts the samples in src and appends the result to dst.
+// A trailing partial sample in src is ignored; use Writer() to convert
+// data which arrives in arbitrary chunks.
+func (c *Converter) Append(dst, src []byte) []byte {
+	inSize := sampleSize(c.from.Bits)
+	outSize := sampleSize(c.to.Bits)
+	inBig := c.from.ByteOrder.isBig()
+	outBig := c.to.ByteOrder.isBig()
+	n := len(src) / inSize
+
+	off := len(dst)
+	if cap(dst)-off < n*outSize {
+		buf := make([]byte, off, off+n*outSize)
+		copy(buf, dst)
+		dst = buf
+	}
+	dst = dst[:off+n*outSize]
+
+	var tmp [1]byte
+	for i := 0; i < n; i++ {
+		in := src[i*inSize : i*inSize+inSize]
+		if c.FromUnsigned8 && c.from.Bits == 8 {
+			tmp[0] = in[0] ^ 0x80
+			in = tmp[:]
+		}
+
+		v := c.requantize(getSample(in, c.from.Bits, inBig))
+
+		out := dst[off+i*outSize:]
+		putSample(out, v, c.to.Bits, outBig)
+
+		if c.ToUnsigned8 && c.to.Bits == 8 {
+			out[0] ^= 0x80
+		}
+	}
+
+	return dst
+}
+
+// requantize rounds the full scale sample v to the target bit depth,
+// applying dither if needed.
+func (c *Converter) requantize(v int32) int32 {
+	if c.to.Bits >= c.from.Bits {
+		return v
+	}
+
+	lsb := int64(1) << uint(32-sampleSize(c.to.Bits)*8)
+	x := int64(v) + lsb/2
+
+	if c.Dither {
+		// The difference of two uniform random values yields noise
+		// with a triangular distribution in the range of ±1 LSB.
+		x += int64(c.random()%uint64(lsb)) - int64(c.random()%uint64(lsb))
+	}
+
+	if x > math.MaxInt32 {
+		x = math.MaxInt32
+	} else if x < math.MinInt32 {
+		x = math.MinInt32
+	}
+
+	return int32(x)
+}
+
+// random returns the next value of a xorshift64* generator.
+func (c *Converter) random() uint64 {
+	c.seed ^= c.seed >> 12
+	c.seed ^= c.seed << 25
+	c.seed ^= c.seed >> 27
+	return c.seed * 2685821657736338717
+}
+
+// Writer returns a writer which converts all data written to it and
+// passes the result on to w. Partial samples are kept until the next
+// write completes them.
+func (c *Converter) Writer(w io.Writer) io.Writer {
+	return &convertWriter{c: c, w: w}
+}
+
+// convertWriter applies a Converter to a stream of writes.
+type convertWriter struct {
+	c       *Converter
+	w       io.Writer
+	partial []byte
+	buf     []byte
+}
+
+func (cw *convertWriter) Write(p []byte) (int, error) {
+	size := sampleSize(cw.c.from.Bits)
+	n := len(p)
+
+	cw.buf = cw.buf[:0]
+
+	if len(cw.partial) > 0 {
+		need := size - len(cw.partial)
+		if len(p) < need {
+			cw.partial = append(cw.partial, p...)
+			return n, nil
+		}
+
+		cw.partial = append(cw.partial, p[:need]...)
+		cw.buf = cw.c.Append(cw.buf, cw.partial)
+		cw.partial = cw.partial[:0]
+		p = p[need:]
+	}
+
+	whole := len(p) - len(p)%size
+	cw.buf = cw.c.Append(cw.buf, p[:whole])
+	cw.partial = append(cw.partial, p[whole:]...)
+
+	if len(cw.buf) > 0 {
+		if _, err := cw.w.Write(cw.buf); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
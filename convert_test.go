@@ -0,0 +1,86 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConverter(t *testing.T) {
+	from := SampleFormat{Bits: 24, Rate: 48000, Channels: 2, ByteOrder: EndianBig}
+	to := SampleFormat{Bits: 16, Rate: 48000, Channels: 2, ByteOrder: EndianLittle}
+
+	c, err := NewConverter(&from, &to)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	src := []byte{
+		0x12, 0x34, 0x56,
+		0x12, 0x34, 0x80, // Rounds up.
+		0xff, 0xff, 0xff,
+		0x7f, 0xff, 0xff, // Must not overflow.
+	}
+
+	want := []byte{0x34, 0x12, 0x35, 0x12, 0x00, 0x00, 0xff, 0x7f}
+	if have := c.Append(nil, src); !bytes.Equal(have, want) {
+		t.Errorf("Append: want %x, have %x", want, have)
+	}
+
+	// Feed the same data in awkward chunks.
+	var buf bytes.Buffer
+	w := c.Writer(&buf)
+	for _, n := range []int{1, 4, 2, 5} {
+		w.Write(src[:n])
+		src = src[n:]
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Writer: want %x, have %x", want, buf.Bytes())
+	}
+}
+
+func TestConverterDither(t *testing.T) {
+	from := SampleFormat{Bits: 16, Rate: 8000, Channels: 1}
+	to := SampleFormat{Bits: 8, Rate: 8000, Channels: 1}
+
+	c, err := NewConverter(&from, &to)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	c.Dither = true
+	c.ToUnsigned8 = true
+
+	src := make([]byte, 2000)
+	dst := c.Append(nil, src)
+
+	var nonzero int
+	for _, v := range dst {
+		if v < 0x7f || v > 0x81 {
+			t.Errorf("Dithered silence exceeds 1 LSB: %#x", v)
+			return
+		}
+
+		if v != 0x80 {
+			nonzero++
+		}
+	}
+
+	if nonzero == 0 {
+		t.Errorf("Dither added no noise")
+	}
+}
+
+func TestConverterMismatch(t *testing.T) {
+	from := FormatCD()
+	to := FormatDAT()
+
+	if _, err := NewConverter(&from, &to); err == nil {
+		t.Errorf("NewConverter accepted differing rates")
+	}
+}
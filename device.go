@@ -3,17 +3,170 @@
 
 package ao
 
-// #include <ao/ao.h>
-import "C"
 import (
-	"errors"
+	"context"
 	"io"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"unsafe"
 )
 
+// output is implemented by the driver specific part of a Device.
+type output interface {
+	// play plays p, which holds whole frames only.
+	play(p []byte) error
+
+	// close closes the output.
+	close() error
+}
+
 // Device holds an opaque type defining output device data.
+//
+// A Device is safe for concurrent use. Writes are serialized, and Close
+// may be called at any time; it interrupts a write in progress at the
+// next chunk boundary.
+//
+// Devices of libao's drivers are all driven from the same thread (see
+// Main). Their writes take turns at each chunk, so a driver which blocks
+// in playback also holds up writes to all other libao devices, as well
+// as calls like Init() and DriverByName(). Go drivers are not affected.
 type Device struct {
-	ptr *C.ao_device
+	*handle
+	wmu      sync.Mutex        // Guards wdone.
+	wdone    chan struct{}     // Closed when the write in progress finishes; nil if none.
+	format   SampleFormat      // Sample format the device was opened with.
+	options  map[string]string // Options the device was opened with.
+	filename string            // Output file; empty for live devices.
+	driver   int               // Id of the driver used to open the device.
+	live     bool              // Is this a live output device?
+	buf      []byte            // Scratch buffer for sample conversions.
+}
+
+// handle holds the state of a Device which is needed to close it.
+// The list of open devices refers to handles rather than devices, so
+// a device which is no longer referenced can be finalized.
+type handle struct {
+	mu      sync.Mutex
+	out     output
+	written uint64 // Number of bytes written so far.
+	partial []byte // Incomplete frame left over from the last write.
+}
+
+// newDevice creates a device for the given driver output and stores
+// copies of the parameters it was opened with. The device is tracked
+// until it is closed, so it can be closed when the library shuts down.
+func newDevice(out output, driver int, filename string, live bool, fmt *SampleFormat, options map[string]string) *Device {
+	d := &Device{
+		handle:   &handle{out: out},
+		format:   *fmt,
+		filename: filename,
+		driver:   driver,
+		live:     live,
+	}
+
+	if len(options) > 0 {
+		d.options = make(map[string]string, len(options))
+		for k, v := range options {
+			d.options[k] = v
+		}
+	}
+
+	track(d.handle)
+	runtime.SetFinalizer(d, (*Device).finalize)
+	return d
+}
+
+// finalize closes a device which was not closed before it became
+// unreachable. If a logger is set, a warning is sent to it.
+func (d *Device) finalize() {
+	d.mu.Lock()
+	open := d.out != nil
+	d.mu.Unlock()
+
+	if !open {
+		return
+	}
+
+	if l := currentLogger(); l != nil {
+		libLock.RLock()
+		name := shortName(d.driver)
+		libLock.RUnlock()
+
+		l.Warn("device was not closed; closing it now",
+			"driver", name, "filename", d.filename)
+	}
+
+	d.handle.close()
+}
+
+// chunkSize returns the number of bytes in roughly 50ms of audio,
+// rounded to whole frames. Long writes are split into chunks of this
+// size when they need to be interruptible.
+func (d *Device) chunkSize() int {
+	size := d.format.FrameSize()
+	if size <= 0 {
+		return 4096
+	}
+
+	frames := d.format.Rate / 20
+	if frames < 1 {
+		frames = 1
+	}
+
+	return frames * size
+}
+
+// Format returns the sample format the device was opened with.
+func (d *Device) Format() SampleFormat {
+	return d.format
+}
+
+// Driver returns the id of the driver the device was opened with.
+func (d *Device) Driver() int {
+	return d.driver
+}
+
+// Filename returns the name of the file being written to.
+// This is empty for live devices and devices opened with OpenWriter().
+func (d *Device) Filename() string {
+	return d.filename
+}
+
+// IsLive returns true if the device was opened with OpenLive().
+func (d *Device) IsLive() bool {
+	return d.live
+}
+
+// Options returns a copy of the options the device was opened with.
+func (d *Device) Options() map[string]string {
+	if d.options == nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(d.options))
+	for k, v := range d.options {
+		m[k] = v
+	}
+
+	return m
+}
+
+// BytesWritten returns the number of bytes written to the device so far.
+func (d *Device) BytesWritten() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written
+}
+
+// FramesWritten returns the number of frames written to the device so far.
+// A frame holds a single sample for each channel.
+func (d *Device) FramesWritten() uint64 {
+	size := d.format.FrameSize()
+	if size == 0 {
+		return 0
+	}
+	return d.BytesWritten() / uint64(size)
 }
 
 // PlayU16 is the same as Play() but accepts a slice of 16 bit PCM sample data.
@@ -27,30 +180,136 @@ func (d *Device) PlayU16(data []uint16) error {
 	return d.Play((*(*[1<<31 - 1]byte)(unsafe.Pointer(&data[0])))[:sz*2])
 }
 
-// Write writes at most len(p) bytes to the underlying device.
+// Write writes p to the underlying device.
 // Samples are interleaved by channels (Time 1, Channel 1; Time 2, Channel 2;
 // Time 1, Channel 1; etc.) in the memory buffer.
 //
+// Only whole frames are passed on to the device. Trailing bytes which do
+// not make up a complete frame are buffered until the next call to Write.
+// They count towards n, so n equals len(p) unless an error occurred.
+//
+// Long writes are passed on in chunks of roughly 50ms of audio. If the
+// device is closed during the write, it stops at the next chunk boundary
+// and returns ErrClosed.
+//
 // Returns an error if playback failed. In which case, the device should
 // be closed.
 func (d *Device) Write(p []byte) (n int, err error) {
-	if len(p) == 0 {
-		return 0, io.EOF
+	return d.WriteContext(context.Background(), p)
+}
+
+// lockWrite waits for other writes to finish, or for ctx to be done.
+// Writers wait on a channel, rather than a mutex, so a cancelled write
+// does not have to wait for another one to finish.
+func (d *Device) lockWrite(ctx context.Context) error {
+	for {
+		d.wmu.Lock()
+		done := d.wdone
+		if done == nil {
+			d.wdone = make(chan struct{})
+		}
+		d.wmu.Unlock()
+
+		if done == nil {
+			return nil
+		}
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+}
+
+// unlockWrite allows the next write to proceed.
+func (d *Device) unlockWrite() {
+	d.wmu.Lock()
+	close(d.wdone)
+	d.wdone = nil
+	d.wmu.Unlock()
+}
 
-	if d.ptr == nil {
-		return 0, errors.New("device is closed")
+// write writes p in chunks, checking ctx before each one.
+// The write lock must be held.
+func (d *Device) write(ctx context.Context, p []byte) (n int, err error) {
+	chunk := d.chunkSize()
+
+	for len(p) > 0 {
+		if err = ctx.Err(); err != nil {
+			return n, err
+		}
+
+		size := chunk
+		if size > len(p) {
+			size = len(p)
+		}
+
+		m, err := d.writeChunk(p[:size])
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+
+		p = p[size:]
+	}
+
+	return n, nil
+}
+
+// writeChunk plays the whole frames in p, along with any partial frame
+// left over from the last write.
+func (d *Device) writeChunk(p []byte) (n int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.out == nil {
+		return 0, ErrClosed
+	}
+
+	size := d.format.FrameSize()
+	if size <= 0 {
+		size = 1
+	}
+
+	// Complete a partial frame left over from a previous write.
+	if len(d.partial) > 0 {
+		need := size - len(d.partial)
+		if len(p) < need {
+			d.partial = append(d.partial, p...)
+			return len(p), nil
+		}
+
+		d.partial = append(d.partial, p[:need]...)
+		if err = d.play(d.partial); err != nil {
+			return 0, err
+		}
+
+		d.partial = d.partial[:0]
+		n, p = need, p[need:]
+	}
+
+	whole := len(p) - len(p)%size
+	if whole > 0 {
+		if err = d.play(p[:whole]); err != nil {
+			return n, err
+		}
 	}
 
-	if C.ao_play(
-		d.ptr,
-		(*C.char)(unsafe.Pointer(&p[0])),
-		C.uint_32(len(p)),
-	) <= 0 {
-		return 0, errors.New("playback failed; device should be closed")
+	d.partial = append(d.partial, p[whole:]...)
+	return n + len(p), nil
+}
+
+// play passes p on to the driver. It must hold whole frames only.
+// h.mu must be held.
+func (h *handle) play(p []byte) error {
+	if err := h.out.play(p); err != nil {
+		return err
 	}
 
-	return len(p), nil
+	h.written += uint64(len(p))
+	return nil
 }
 
 // Play is an alias for Device.Write(). Refer to its documentation for details.
@@ -59,21 +318,61 @@ func (d *Device) Play(p []byte) error {
 	return err
 }
 
+// WriteContext is like Write, but checks ctx before each chunk of roughly
+// 50ms of audio. If ctx is cancelled or its deadline expires, playback stops
+// at the next chunk boundary. It returns the number of bytes played and
+// ctx.Err(). This also applies while waiting for another write to finish.
+func (d *Device) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	if err = d.lockWrite(ctx); err != nil {
+		return 0, err
+	}
+
+	defer d.unlockWrite()
+	return d.write(ctx, p)
+}
+
+// PlayContext is an alias for Device.WriteContext().
+// Refer to its documentation for details.
+func (d *Device) PlayContext(ctx context.Context, p []byte) error {
+	_, err := d.WriteContext(ctx, p)
+	return err
+}
+
 // Close closes the audio device and frees the memory allocated
-// by the device structure.
+// by the device structure. A write in progress is stopped at the next
+// chunk boundary; Close waits for it to do so. Subsequent calls to
+// Close do nothing and return nil.
 //
 // An error is returned if closing of the device failed.
 // If this device was writing to a file, the file may be corrupted.
+// If an incomplete frame was still buffered, it is discarded and
+// ErrPartialFrame is returned.
 func (d *Device) Close() error {
+	runtime.SetFinalizer(d, nil)
+	return d.handle.close()
+}
+
+// close closes the driver output, if it is still open.
+func (h *handle) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.out == nil {
+		return nil
+	}
+
 	var err error
+	if len(h.partial) > 0 {
+		err = ErrPartialFrame
+		h.partial = nil
+	}
 
-	if d.ptr != nil {
-		if C.ao_close(d.ptr) <= 0 {
-			err = errors.New("failed to close device correctly")
-		}
-		d.ptr = nil
+	if cerr := h.out.close(); cerr != nil {
+		err = cerr
 	}
 
+	h.out = nil
+	untrack(h)
 	return err
 }
 
@@ -83,46 +382,101 @@ func (d *Device) Close() error {
 // The driver id can be retrieved by either DriverId() or DefaultDriver().
 // Live output drivers cannot be used with this function. Use OpenLive instead.
 // Some file formats (notably .WAV) cannot be correctly written to non-seekable
-// files (like stdout).
+// files (like stdout). See OpenWriter() for details.
 //
 // The sample format defines the format of the output stream.
+// It is checked with SampleFormat.Validate() before the device is opened.
 //
 // If overwrite is true, the file is automatically overwritten.
 // Otherwise a preexisting file will cause the function to report a failure.
+// The filename "-" denotes standard output, which is never closed.
 //
 // The optional map defines device configuration settings.
 // Refer to https://xiph.org/ao/doc/drivers.html for a list of options
 // supported by the current driver.
 //
-// Returns an error if the device could not be opened.
-// Be sure to call Device.Close() once you are done with it.
+// Returns an *OpenError if the device could not be opened. Its Err field
+// holds one of the Err*** values; e.g.: ErrFileExists.
+// Be sure to call Device.Close() once you are done with it. Devices which
+// are still open when the library is shut down are closed automatically.
 func OpenFile(driver int, filename string, overwrite bool, fmt *SampleFormat, options map[string]string) (*Device, error) {
-	coptions := makeOptions(options)
-	cfilename := C.CString(filename)
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return nil, &OpenError{Filename: filename, Err: ErrNotInitialized}
+	}
 
-	defer func() {
-		freeOptions(coptions)
-		C.free(unsafe.Pointer(cfilename))
-	}()
+	if err := fmt.Validate(); err != nil {
+		return nil, &OpenError{Driver: shortName(driver), Filename: filename, Err: err}
+	}
 
-	var coverwrite C.int
-	if overwrite {
-		coverwrite = 1
+	out, err := openFile(driver, filename, overwrite, fmt, options)
+	if err != nil {
+		return nil, openError(driver, filename, err)
 	}
 
-	dev, err := C.ao_open_file(
-		C.int(driver),
-		cfilename,
-		coverwrite,
-		fmt.toC(),
-		coptions,
-	)
+	return newDevice(out, driver, filename, false, fmt, options), nil
+}
 
+// OpenFileAuto is like OpenFile(), but picks the file output driver from
+// the extension of filename. Refer to DriverForExtension() for details.
+//
+// Returns an *OpenError wrapping ErrUnknownExtension if no driver uses the
+// extension, or ErrNotFile if it belongs to a live output driver.
+func OpenFileAuto(filename string, overwrite bool, fmt *SampleFormat, options map[string]string) (*Device, error) {
+	driver, err := DriverForExtension(filepath.Ext(filename))
 	if err != nil {
-		return nil, err
+		return nil, &OpenError{Filename: filename, Err: err}
 	}
 
-	return &Device{dev}, nil
+	return OpenFile(driver, filename, overwrite, fmt, options)
+}
+
+// OpenWriter opens a file output driver which writes to w, rather than
+// to a named file. This allows output to go to a network connection or
+// a bytes.Buffer, for example.
+//
+// The driver id can be retrieved by either DriverId() or DefaultDriver().
+// Live output drivers cannot be used with this function.
+// The sample format and options are the same as for OpenFile().
+//
+// Go drivers receive w directly. libao's drivers write to an OS pipe,
+// whose contents are buffered and copied into w by a separate goroutine.
+// A slow writer does not hold up other devices; once about a megabyte
+// is waiting for it, Device.Write() blocks until it catches up. Since
+// this happens asynchronously, an error returned by w is reported by a
+// later call to Device.Write() or by Device.Close(). This is not
+// supported on Windows; ErrUnsupported is returned there.
+//
+// Formats which store the length of the data in a header cannot update
+// it once playback is done, unless a Go driver writes to an io.WriteSeeker.
+// libao's WAV driver leaves the header of a pipe incomplete. The pure Go
+// WAV and AU encoders mark the length as unknown instead, which most
+// players accept. Prefer the raw or AU driver when streaming to clients
+// which require correct lengths.
+//
+// Returns an *OpenError if the device could not be opened.
+// Be sure to call Device.Close() once you are done with it; w is not
+// closed along with it.
+func OpenWriter(driver int, w io.Writer, fmt *SampleFormat, options map[string]string) (*Device, error) {
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return nil, &OpenError{Err: ErrNotInitialized}
+	}
+
+	if err := fmt.Validate(); err != nil {
+		return nil, &OpenError{Driver: shortName(driver), Err: err}
+	}
+
+	out, err := openWriter(driver, w, fmt, options)
+	if err != nil {
+		return nil, openError(driver, "", err)
+	}
+
+	return newDevice(out, driver, "", false, fmt, options), nil
 }
 
 // OpenLive opens a live playback audio device for output.
@@ -131,21 +485,32 @@ func OpenFile(driver int, filename string, overwrite bool, fmt *SampleFormat, op
 // File output drivers cannot be used with this function. Use OpenFile() instead.
 //
 // The sample format defines the format of the output stream.
+// It is checked with SampleFormat.Validate() before the device is opened.
 //
 // The optional map defines device configuration settings.
 // Refer to https://xiph.org/ao/doc/drivers.html for a list of options
 // supported by the current driver.
 //
-// Returns an error if the device could not be opened.
-// Be sure to call Device.Close() once you are done with it.
+// Returns an *OpenError if the device could not be opened. Its Err field
+// holds one of the Err*** values; e.g.: ErrOpenDevice.
+// Be sure to call Device.Close() once you are done with it. Devices which
+// are still open when the library is shut down are closed automatically.
 func OpenLive(driver int, fmt *SampleFormat, options map[string]string) (*Device, error) {
-	coptions := makeOptions(options)
-	defer freeOptions(coptions)
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return nil, &OpenError{Err: ErrNotInitialized}
+	}
 
-	dev, err := C.ao_open_live(C.int(driver), fmt.toC(), coptions)
-	if dev == nil {
-		return nil, err
+	if err := fmt.Validate(); err != nil {
+		return nil, &OpenError{Driver: shortName(driver), Err: err}
+	}
+
+	out, err := openLive(driver, fmt, options)
+	if err != nil {
+		return nil, openError(driver, "", err)
 	}
 
-	return &Device{dev}, nil
+	return newDevice(out, driver, "", true, fmt, options), nil
 }
@@ -0,0 +1,185 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer which is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// openNull opens a live device on the null driver.
+func openNull(t *testing.T) *Device {
+	t.Helper()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := OpenLive(driver, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dev
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	dev := openNull(t)
+	defer dev.Close()
+
+	// Odd sizes make writes carry partial frames into the next one.
+	const writers, writes, size = 8, 50, 1001
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			buf := make([]byte, size)
+			samples := make([]int16, 64)
+
+			for j := 0; j < writes; j++ {
+				if err := dev.Play(buf); err != nil {
+					t.Error(err)
+					return
+				}
+
+				if err := dev.PlayInt16(samples); err != nil {
+					t.Error(err)
+					return
+				}
+
+				dev.BytesWritten()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	want := uint64(writers * writes * (size + 128))
+	want -= want % uint64(format.FrameSize())
+
+	if have := dev.BytesWritten(); have != want {
+		t.Fatalf("bytes written: have %d, want %d", have, want)
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	dev := openNull(t)
+
+	// Play in the background until the device is closed.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, dev.chunkSize()*4)
+			for {
+				err := dev.Play(buf)
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	var closers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		closers.Add(1)
+		go func() {
+			defer closers.Done()
+			if err := dev.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	closers.Wait()
+	wg.Wait()
+
+	if err := dev.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+}
+
+func TestDeviceFinalizer(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	var logs syncBuffer
+	if err := SetLogger(slog.New(slog.NewTextHandler(&logs, nil))); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogger(nil)
+
+	// Keep only the handle, to see it closed.
+	dev := openNull(t)
+	h := dev.handle
+	dev = nil
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+
+		h.mu.Lock()
+		closed := h.out == nil
+		h.mu.Unlock()
+
+		if closed {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.out != nil {
+		t.Fatal("leaked device was not closed")
+	}
+
+	if !strings.Contains(logs.String(), `level=WARN msg="device was not closed; closing it now" driver=null`) {
+		t.Fatalf("missing warning; log holds %q", logs.String())
+	}
+}
@@ -0,0 +1,280 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Driver is an output driver implemented in Go. Drivers registered with
+// RegisterDriver() are listed by Drivers(), can be found by DriverByName()
+// and are opened through OpenLive() or OpenFile(), just like the drivers
+// provided by libao.
+type Driver interface {
+	// Info describes the driver. Its Type field determines whether the
+	// driver is opened by OpenLive() or OpenFile(). The ID and ShortName
+	// fields are assigned by RegisterDriver().
+	Info() DriverInfo
+
+	// Open opens a new output with the given sample format and options.
+	// The format has been validated at this point.
+	//
+	// For file drivers, w receives the encoded output. It is owned by the
+	// caller and must not be closed. For live drivers, w is nil.
+	//
+	// The returned writer receives interleaved PCM data in whole frames.
+	// Closing it must flush and release all resources held by the output.
+	Open(w io.Writer, sf *SampleFormat, options map[string]string) (io.WriteCloser, error)
+}
+
+// registeredDriver holds a driver and the name it was registered with.
+type registeredDriver struct {
+	name   string
+	driver Driver
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     []registeredDriver
+)
+
+// RegisterDriver makes a Go driver available under the given name.
+// It is assigned its own driver id, distinct from those of libao.
+// As with libao's drivers, it can only be used between calls to Init()
+// and Shutdown().
+//
+// If a registered driver has the same name as a libao driver,
+// DriverByName() returns the registered one.
+//
+// RegisterDriver panics if d is nil, or if a driver with the same name
+// has already been registered.
+func RegisterDriver(name string, d Driver) {
+	if d == nil {
+		panic("ao: RegisterDriver driver is nil")
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	for _, rd := range registry {
+		if rd.name == name {
+			panic("ao: RegisterDriver called twice for driver " + name)
+		}
+	}
+
+	registry = append(registry, registeredDriver{name, d})
+}
+
+// registered returns the Go driver with the given id, if any.
+// libLock must be held for reading.
+func registered(id int) (registeredDriver, bool) {
+	if !initialized() {
+		return registeredDriver{}, false
+	}
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	index := id - goDriverBase
+	if index < 0 || index >= len(registry) {
+		return registeredDriver{}, false
+	}
+
+	return registry[index], true
+}
+
+// registeredCount returns the number of registered Go drivers.
+func registeredCount() int {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return len(registry)
+}
+
+// defaultDriver returns the id of libao's default driver. If there
+// is none, the registered live driver with the highest priority is
+// returned, provided its priority is above zero.
+// libLock must be held for reading.
+func defaultDriver() int {
+	if !initialized() {
+		return -1
+	}
+
+	if id := libDefaultDriver(); id != -1 {
+		return id
+	}
+
+	id, priority := -1, 0
+	for _, info := range goDriverInfoList() {
+		if info.Type == DriverLive && info.Priority > priority {
+			id, priority = info.ID, info.Priority
+		}
+	}
+
+	return id
+}
+
+// driverID returns the id of the driver with the given name, or -1.
+// libLock must be held for reading.
+func driverID(name string) int {
+	if !initialized() {
+		return -1
+	}
+
+	for _, info := range goDriverInfoList() {
+		if info.ShortName == name {
+			return info.ID
+		}
+	}
+	return libDriverID(name)
+}
+
+// driverInfo returns information on the driver with the given id.
+// libLock must be held for reading.
+func driverInfo(id int) (DriverInfo, bool) {
+	if !initialized() {
+		return DriverInfo{}, false
+	}
+
+	rd, ok := registered(id)
+	if !ok {
+		return libDriverInfo(id)
+	}
+
+	info := rd.driver.Info()
+	info.ID = id
+	info.ShortName = rd.name
+	info.Options = append([]string(nil), info.Options...)
+	return info, true
+}
+
+// driverInfoList returns information on all available drivers.
+// libLock must be held for reading.
+func driverInfoList() []DriverInfo {
+	if !initialized() {
+		return nil
+	}
+
+	return append(libDriverInfoList(), goDriverInfoList()...)
+}
+
+// goDriverInfoList returns information on all registered Go drivers.
+func goDriverInfoList() []DriverInfo {
+	var out []DriverInfo
+
+	for i, n := 0, registeredCount(); i < n; i++ {
+		if info, ok := driverInfo(goDriverBase + i); ok {
+			out = append(out, info)
+		}
+	}
+
+	return out
+}
+
+// openLive opens a live output on the given driver.
+func openLive(driver int, sf *SampleFormat, options map[string]string) (output, error) {
+	rd, ok := registered(driver)
+	if !ok {
+		return libOpenLive(driver, sf, options)
+	}
+
+	if rd.driver.Info().Type != DriverLive {
+		return nil, ErrNotLive
+	}
+
+	wc, err := rd.driver.Open(nil, sf, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &goOutput{wc: wc}, nil
+}
+
+// openFile opens a file output on the given driver.
+func openFile(driver int, filename string, overwrite bool, sf *SampleFormat, options map[string]string) (output, error) {
+	rd, ok := registered(driver)
+	if !ok {
+		return libOpenFile(driver, filename, overwrite, sf, options)
+	}
+
+	if rd.driver.Info().Type != DriverFile {
+		return nil, ErrNotFile
+	}
+
+	// As with libao, "-" writes to stdout, which is left open.
+	if filename == "-" {
+		wc, err := rd.driver.Open(os.Stdout, sf, options)
+		if err != nil {
+			return nil, err
+		}
+		return &goOutput{wc: wc}, nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+
+	fd, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, ErrFileExists
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+
+	wc, err := rd.driver.Open(fd, sf, options)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	return &goOutput{wc: wc, fd: fd}, nil
+}
+
+// openWriter opens a file output on the given driver, which writes to w.
+func openWriter(driver int, w io.Writer, sf *SampleFormat, options map[string]string) (output, error) {
+	rd, ok := registered(driver)
+	if !ok {
+		return libOpenWriter(driver, w, sf, options)
+	}
+
+	if rd.driver.Info().Type != DriverFile {
+		return nil, ErrNotFile
+	}
+
+	wc, err := rd.driver.Open(w, sf, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &goOutput{wc: wc}, nil
+}
+
+// goOutput plays audio through a Go driver.
+type goOutput struct {
+	wc io.WriteCloser
+	fd *os.File // Output file for file drivers.
+}
+
+func (o *goOutput) play(p []byte) error {
+	_, err := o.wc.Write(p)
+	return err
+}
+
+func (o *goOutput) close() error {
+	err := o.wc.Close()
+
+	if o.fd != nil {
+		if cerr := o.fd.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
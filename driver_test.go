@@ -0,0 +1,140 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// recorder is a live Go driver which records everything written to it.
+type recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Info() DriverInfo {
+	return DriverInfo{Type: DriverLive, Name: "Test recorder", Extension: "rec"}
+}
+
+func (r *recorder) Open(w io.Writer, sf *SampleFormat, options map[string]string) (io.WriteCloser, error) {
+	return r, nil
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *recorder) Close() error { return nil }
+
+var (
+	testRecorder     = &recorder{}
+	registerRecorder sync.Once
+)
+
+func TestRegisterDriver(t *testing.T) {
+	const name = "test-recorder"
+
+	registerRecorder.Do(func() { RegisterDriver(name, testRecorder) })
+
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	info, err := DriverInfoByID(driver)
+	if err != nil || info.ShortName != name || info.Type != DriverLive {
+		t.Errorf("DriverInfoByID: have %+v, %v", info, err)
+	}
+
+	var listed bool
+	for _, di := range Drivers() {
+		listed = listed || di.ID == driver
+	}
+
+	if !listed {
+		t.Errorf("Driver %q not listed by Drivers()", name)
+	}
+
+	dev, err := OpenLive(driver, format, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	testRecorder.buf.Reset()
+	dev.Play([]byte{1, 2, 3, 4, 5})
+	dev.Close()
+
+	if have := testRecorder.buf.Bytes(); !bytes.Equal(have, []byte{1, 2, 3, 4}) {
+		t.Errorf("Recorded %x, want 01020304", have)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Duplicate RegisterDriver did not panic")
+		}
+	}()
+
+	RegisterDriver(name, testRecorder)
+}
+
+func TestDriverForExtension(t *testing.T) {
+	registerRecorder.Do(func() { RegisterDriver("test-recorder", testRecorder) })
+
+	Init()
+	defer Shutdown()
+
+	wav, err := DriverByName("wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{".wav", "wav", ".WAV"} {
+		if id, err := DriverForExtension(ext); err != nil || id != wav {
+			t.Errorf("%q: have %d, %v; want %d", ext, id, err, wav)
+		}
+	}
+
+	if _, err = DriverForExtension(".xyz"); !errors.Is(err, ErrUnknownExtension) {
+		t.Errorf("unknown extension: have %v, want %v", err, ErrUnknownExtension)
+	}
+
+	if _, err = DriverForExtension(".rec"); !errors.Is(err, ErrNotFile) {
+		t.Errorf("live driver: have %v, want %v", err, ErrNotFile)
+	}
+}
+
+func TestOpenFileAuto(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	dir := t.TempDir()
+
+	dev, err := OpenFileAuto(filepath.Join(dir, "out.raw"), false, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dev.Close()
+
+	if info, _ := DriverInfoByID(dev.Driver()); info == nil || info.Extension != "raw" {
+		t.Fatalf("opened with driver %+v", info)
+	}
+
+	_, err = OpenFileAuto(filepath.Join(dir, "out.xyz"), false, format, nil)
+	if !errors.Is(err, ErrUnknownExtension) {
+		t.Fatalf("unknown extension: have %v, want %v", err, ErrUnknownExtension)
+	}
+}
@@ -0,0 +1,67 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+// DriverType defines the kind of output a driver produces.
+type DriverType int
+
+// Known driver types.
+const (
+	DriverLive DriverType = 1 // Live output to a sound device.
+	DriverFile DriverType = 2 // Output to a file.
+)
+
+// String returns a human readable name for the driver type.
+func (dt DriverType) String() string {
+	switch dt {
+	case DriverLive:
+		return "live"
+	case DriverFile:
+		return "file"
+	}
+	return "unknown"
+}
+
+// DriverInfo describes a single output driver.
+type DriverInfo struct {
+	ID                  int        // Driver id, as accepted by OpenLive() and OpenFile().
+	Type                DriverType // Live or file output.
+	Name                string     // Full name of the driver.
+	ShortName           string     // Short name, as accepted by DriverByName().
+	Author              string     // Driver author.
+	Comment             string     // Driver comments.
+	PreferredByteFormat ByteOrder  // Byte order the driver prefers.
+	Priority            int        // Priority when selecting a default driver.
+	Options             []string   // Option keys supported by the driver.
+	Extension           string     // Normal file name extension, without the dot. File drivers only.
+}
+
+// Drivers returns information on all available drivers: those loaded by
+// libao, followed by those registered with RegisterDriver().
+// The result is empty if the library is not initialized.
+func Drivers() []DriverInfo {
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	return driverInfoList()
+}
+
+// DriverInfoByID returns information on the driver with the given id.
+//
+// Returns ErrNoDriver if no such driver exists, or ErrNotInitialized
+// if the library is not initialized.
+func DriverInfoByID(id int) (*DriverInfo, error) {
+	libLock.RLock()
+	defer libLock.RUnlock()
+
+	if !initialized() {
+		return nil, ErrNotInitialized
+	}
+
+	info, ok := driverInfo(id)
+	if !ok {
+		return nil, ErrNoDriver
+	}
+	return &info, nil
+}
@@ -0,0 +1,202 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// unknownSize is written to header size fields if the size of the data
+// is not known when the header is written, and can not be updated later,
+// because the output is not seekable.
+const unknownSize = math.MaxUint32
+
+// Encoder writes PCM data to an io.Writer in a specific file format.
+// It is created by NewWAVEncoder, NewAUEncoder or NewRawEncoder and
+// implements io.WriteCloser.
+//
+// Data passed to Write is interleaved PCM in the sample format given to
+// the constructor; it is converted to the byte order and signedness the
+// file format requires.
+//
+// If the underlying writer implements io.WriteSeeker and can actually seek,
+// Close rewrites the header with the final data size. Otherwise the header
+// states an unknown size, which most decoders accept for streaming input.
+// This includes an *os.File for a pipe or terminal. Close does not close
+// the underlying writer.
+type Encoder struct {
+	w       io.Writer
+	conv    io.Writer
+	format  SampleFormat
+	header  func(size int64) []byte // Builds the header; nil for headerless formats.
+	pad     bool                    // Pad the data to an even size, as RIFF requires.
+	seeker  io.WriteSeeker          // w, if it is seekable; nil otherwise.
+	start   int64                   // Offset of the header in a seekable writer.
+	in      int64                   // Number of bytes passed to Write.
+	written int64                   // Number of data bytes written to w.
+	closed  bool
+}
+
+// newEncoder creates an encoder which writes data in the given target
+// format and writes the initial header, if any.
+func newEncoder(w io.Writer, sf, target *SampleFormat, unsigned8, pad bool, header func(int64) []byte) (*Encoder, error) {
+	conv, err := NewConverter(sf, target)
+	if err != nil {
+		return nil, err
+	}
+
+	conv.ToUnsigned8 = unsigned8
+
+	e := &Encoder{
+		w:      w,
+		format: *sf,
+		header: header,
+		pad:    pad,
+	}
+
+	e.conv = conv.Writer(writerFunc(func(p []byte) (int, error) {
+		n, err := e.w.Write(p)
+		e.written += int64(n)
+		return n, err
+	}))
+
+	if header != nil {
+		// Files which can not seek, such as pipes, fail here.
+		size := int64(-1)
+		if ws, ok := w.(io.WriteSeeker); ok {
+			if e.start, err = ws.Seek(0, io.SeekCurrent); err == nil {
+				e.seeker = ws
+				size = 0
+			}
+		}
+
+		if _, err = w.Write(header(size)); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Format returns the sample format of the data passed to Write.
+func (e *Encoder) Format() SampleFormat {
+	return e.format
+}
+
+// Write encodes p, which holds interleaved PCM data.
+func (e *Encoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, ErrClosed
+	}
+
+	n, err := e.conv.Write(p)
+	e.in += int64(n)
+	return n, err
+}
+
+// Close finalizes the output. If the underlying writer is seekable,
+// the header is updated with the final data size.
+//
+// Returns ErrPartialFrame if the data written did not end on a frame
+// boundary. The incomplete frame is discarded.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+
+	if e.header != nil {
+		if e.pad && e.written%2 == 1 {
+			if _, err := e.w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+
+		if e.seeker != nil {
+			if err := e.rewriteHeader(e.seeker); err != nil {
+				return err
+			}
+		}
+	}
+
+	if size := e.format.FrameSize(); size > 0 && e.in%int64(size) != 0 {
+		return ErrPartialFrame
+	}
+
+	return nil
+}
+
+// rewriteHeader writes the header with the final data size.
+func (e *Encoder) rewriteHeader(ws io.WriteSeeker) error {
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err = ws.Seek(e.start, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err = ws.Write(e.header(e.written)); err != nil {
+		return err
+	}
+
+	_, err = ws.Seek(end, io.SeekStart)
+	return err
+}
+
+// NewRawEncoder creates an encoder which writes headerless PCM data in
+// the given sample format. Data is passed through unchanged.
+func NewRawEncoder(w io.Writer, sf *SampleFormat) (*Encoder, error) {
+	return newEncoder(w, sf, sf, false, false, nil)
+}
+
+// NewAUEncoder creates an encoder which writes a Sun AU file.
+// AU files hold signed, big-endian samples.
+func NewAUEncoder(w io.Writer, sf *SampleFormat) (*Encoder, error) {
+	target := *sf
+	target.ByteOrder = EndianBig
+
+	return newEncoder(w, sf, &target, false, false, func(size int64) []byte {
+		var encoding uint32
+		switch sf.Bits {
+		case 8:
+			encoding = 2
+		case 16:
+			encoding = 3
+		case 24:
+			encoding = 4
+		case 32:
+			encoding = 5
+		}
+
+		h := make([]byte, 24)
+		binary.BigEndian.PutUint32(h[0:], 0x2e736e64) // ".snd"
+		binary.BigEndian.PutUint32(h[4:], uint32(len(h)))
+		binary.BigEndian.PutUint32(h[8:], headerSize(size))
+		binary.BigEndian.PutUint32(h[12:], encoding)
+		binary.BigEndian.PutUint32(h[16:], uint32(sf.Rate))
+		binary.BigEndian.PutUint32(h[20:], uint32(sf.Channels))
+		return h
+	})
+}
+
+// headerSize returns the value to store in a 32-bit header size field.
+// A negative size, or one which does not fit, is stored as unknownSize.
+func headerSize(size int64) uint32 {
+	if size < 0 || size >= unknownSize {
+		return unknownSize
+	}
+	return uint32(size)
+}
+
+// writerFunc turns a function into an io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,235 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// memFile is an in-memory io.WriteSeeker.
+type memFile struct {
+	buf []byte
+	pos int
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if end := f.pos + len(p); end > len(f.buf) {
+		f.buf = append(f.buf, make([]byte, end-len(f.buf))...)
+	}
+	n := copy(f.buf[f.pos:], p)
+	f.pos += n
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(f.pos)
+	case io.SeekEnd:
+		offset += int64(len(f.buf))
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	f.pos = int(offset)
+	return offset, nil
+}
+
+func TestWAVEncoder(t *testing.T) {
+	sf := SampleFormat{Bits: 16, Rate: 8000, Channels: 2, ByteOrder: EndianBig, Matrix: MatrixDefault}
+	data := []byte{0x12, 0x34, 0x56, 0x78}
+
+	var f memFile
+	enc, err := NewWAVEncoder(&f, &sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	enc.Write(data)
+	enc.Close()
+
+	le := binary.LittleEndian
+	if len(f.buf) != 44+len(data) {
+		t.Errorf("Want %d bytes, have %d", 44+len(data), len(f.buf))
+		return
+	}
+
+	if string(f.buf[0:4]) != "RIFF" || string(f.buf[8:12]) != "WAVE" || le.Uint16(f.buf[20:]) != wavFormatPCM {
+		t.Errorf("Invalid header: %x", f.buf[:44])
+	}
+
+	if le.Uint32(f.buf[4:]) != 36+4 || le.Uint32(f.buf[40:]) != 4 {
+		t.Errorf("Sizes were not updated: %x", f.buf[:44])
+	}
+
+	if want := []byte{0x34, 0x12, 0x78, 0x56}; !bytes.Equal(f.buf[44:], want) {
+		t.Errorf("Data: want %x, have %x", want, f.buf[44:])
+	}
+}
+
+func TestWAVEncoderExtensible(t *testing.T) {
+	sf := SampleFormat{Bits: 24, Rate: 48000, Channels: 6, ByteOrder: EndianLittle, Matrix: Matrix51}
+
+	var buf bytes.Buffer
+	enc, err := NewWAVEncoder(&buf, &sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	enc.Write(make([]byte, 18*10+1))
+	if err = enc.Close(); err != ErrPartialFrame {
+		t.Errorf("Close: want ErrPartialFrame, have %v", err)
+	}
+
+	h := buf.Bytes()
+	le := binary.LittleEndian
+
+	if le.Uint16(h[20:]) != wavFormatExtensible || le.Uint32(h[16:]) != 40 {
+		t.Errorf("Want WAVE_FORMAT_EXTENSIBLE header: %x", h[:68])
+	}
+
+	if mask := le.Uint32(h[40:]); mask != 0x3f {
+		t.Errorf("Channel mask: want 0x3f, have %#x", mask)
+	}
+
+	if le.Uint32(h[4:]) != unknownSize || le.Uint32(h[64:]) != unknownSize {
+		t.Errorf("Want unknown sizes for non-seekable output: %x", h[:68])
+	}
+
+	sf.Matrix = "L,R,C,LFE,BL,BR"
+	if mask, order, err := wavLayout(&sf); mask != 0x3f || order != nil || err != nil {
+		t.Errorf("Layout: want 0x3f, nil, nil; have %#x, %v, %v", mask, order, err)
+	}
+
+	sf.Matrix = "M,C,L,R,LFE,BL"
+	if _, err = NewWAVEncoder(&buf, &sf); !errors.Is(err, ErrBadFormat) {
+		t.Errorf("Shared speaker position: want ErrBadFormat, have %v", err)
+	}
+}
+
+func TestWAVEncoderReorder(t *testing.T) {
+	// Matrix71 stores BR before BL; WAV wants BL, BR, SL, SR.
+	sf := SampleFormat{Bits: 8, Rate: 8000, Channels: 8, Matrix: Matrix71}
+	frame := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+
+	var buf bytes.Buffer
+	enc, err := NewWAVEncoder(&buf, &sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Split the frames across writes.
+	data := append(frame, frame...)
+	enc.Write(data[:5])
+	enc.Write(data[5:])
+
+	if err = enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := buf.Bytes()
+	if mask := binary.LittleEndian.Uint32(h[40:]); mask != 0x63f {
+		t.Errorf("Channel mask: want 0x63f, have %#x", mask)
+	}
+
+	// 8-bit WAV samples are unsigned.
+	want := []byte{0x80, 0x81, 0x82, 0x83, 0x85, 0x84, 0x86, 0x87}
+	want = append(want, want...)
+
+	if have := h[len(h)-16:]; !bytes.Equal(have, want) {
+		t.Errorf("Data: want %x, have %x", want, have)
+	}
+}
+
+func TestEncoderPipe(t *testing.T) {
+	sf := SampleFormat{Bits: 16, Rate: 8000, Channels: 1}
+	data := []byte{0x12, 0x34, 0x56, 0x78}
+
+	for name, encoder := range map[string]func(io.Writer, *SampleFormat) (*Encoder, error){
+		"wav": NewWAVEncoder,
+		"au":  NewAUEncoder,
+	} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := make(chan []byte)
+		go func() {
+			b, _ := io.ReadAll(r)
+			r.Close()
+			out <- b
+		}()
+
+		// An *os.File is an io.WriteSeeker, but a pipe can not seek.
+		enc, err := encoder(w, &sf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		enc.Write(data)
+		if err = enc.Close(); err != nil {
+			t.Fatalf("%s: close: %v", name, err)
+		}
+
+		w.Close()
+		b := <-out
+
+		if !bytes.Contains(b, []byte{0xff, 0xff, 0xff, 0xff}) || len(b) < len(data) {
+			t.Errorf("%s: want a header of unknown size: %x", name, b)
+		}
+	}
+}
+
+func TestAUEncoder(t *testing.T) {
+	sf := SampleFormat{Bits: 8, Rate: 8000, Channels: 1}
+	data := []byte{0x80, 0x00, 0x7f}
+
+	var f memFile
+	enc, err := NewAUEncoder(&f, &sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	enc.Write(data)
+	enc.Close()
+
+	be := binary.BigEndian
+	if string(f.buf[0:4]) != ".snd" || be.Uint32(f.buf[8:]) != 3 || be.Uint32(f.buf[12:]) != 2 {
+		t.Errorf("Invalid header: %x", f.buf[:24])
+	}
+
+	if !bytes.Equal(f.buf[24:], data) {
+		t.Errorf("Data: want %x, have %x", data, f.buf[24:])
+	}
+}
+
+func TestRawEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	sf := FormatCD()
+	enc, err := NewRawEncoder(&buf, &sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	enc.Write(data[:3])
+	enc.Write(data[3:])
+	enc.Close()
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Data: want %x, have %x", data, buf.Bytes())
+	}
+}
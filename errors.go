@@ -0,0 +1,57 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import "errors"
+
+// Errors reported by libao. These can be tested for with errors.Is().
+var (
+	ErrNoDriver    = errors.New("no driver corresponds to the given driver id")
+	ErrNotFile     = errors.New("driver is not a file output driver")
+	ErrNotLive     = errors.New("driver is not a live output driver")
+	ErrBadOption   = errors.New("a valid option key has an invalid value")
+	ErrOpenDevice  = errors.New("cannot open the device")
+	ErrOpenFile    = errors.New("cannot open the file")
+	ErrFileExists  = errors.New("file already exists")
+	ErrBadFormat   = errors.New("requested sample format is not supported")
+	ErrFail        = errors.New("unknown failure")
+	ErrUnsupported = errors.New("operation is not supported by the driver")
+)
+
+// Errors reported by the library, a Device and its players.
+var (
+	ErrNotInitialized   = errors.New("library is not initialized")
+	ErrInitialized      = errors.New("library is already initialized")
+	ErrUnknownExtension = errors.New("no driver corresponds to the file name extension")
+	ErrClosed           = errors.New("device is closed")
+	ErrPartialFrame     = errors.New("incomplete trailing frame was discarded")
+	ErrStopped          = errors.New("player is stopped")
+)
+
+// OpenError records a failure to open a device, along with the driver
+// and file involved.
+type OpenError struct {
+	Driver   string // Short name of the driver, if known.
+	Filename string // Name of the file being opened; empty for live output.
+	Err      error  // One of the Err*** values.
+}
+
+func (e *OpenError) Error() string {
+	msg := "open"
+
+	if len(e.Driver) > 0 {
+		msg += " " + e.Driver
+	}
+
+	if len(e.Filename) > 0 {
+		msg += " " + e.Filename
+	}
+
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *OpenError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,72 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+//go:build cgo
+
+package ao
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// TestLeaks opens and closes a large number of devices and ensures all
+// C memory allocated for their arguments has been released.
+// Every C allocation goes through cString(), so removing any of the
+// matching calls to cFree() makes this test fail.
+func TestLeaks(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	sf := *format
+	sf.Matrix = MatrixDefault
+
+	before := atomic.LoadInt64(&cAllocs)
+
+	for i := 0; i < 5000; i++ {
+		dev, err := OpenLive(driver, &sf, options)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err = dev.Close(); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if n := atomic.LoadInt64(&cAllocs) - before; n != 0 {
+			t.Errorf("Iteration %d: %d C allocations were not released", i, n)
+			return
+		}
+	}
+
+	// Driver lookups copy the name into C memory.
+	for i := 0; i < 1000; i++ {
+		DriverByName(driverName)
+		DriverByName("no such driver")
+	}
+
+	if n := atomic.LoadInt64(&cAllocs) - before; n != 0 {
+		t.Errorf("DriverByName: %d C allocations were not released", n)
+		return
+	}
+
+	// Failed opens must release their arguments as well.
+	for i := 0; i < 1000; i++ {
+		if _, err = OpenFile(driver, "", true, &sf, options); err == nil {
+			t.Errorf("OpenFile on live driver succeeded")
+			return
+		}
+	}
+
+	if n := atomic.LoadInt64(&cAllocs) - before; n != 0 {
+		t.Errorf("%d C allocations were not released", n)
+	}
+}
@@ -0,0 +1,455 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+//go:build cgo
+
+package ao
+
+// #cgo pkg-config: ao
+//
+// #include <stdlib.h>
+// #include <unistd.h>
+// #include <ao/ao.h>
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// This file implements the driver functions on top of libao.
+// It is used whenever cgo is available. All calls into libao go through
+// libCall(), so they are made from the executor's thread.
+
+// goDriverBase is the id of the first driver registered with
+// RegisterDriver(). It is well above any id libao hands out.
+const goDriverBase = 1 << 16
+
+func init() {
+	// Keep the main goroutine on the main thread, so Main() can hand it
+	// over to the executor.
+	runtime.LockOSThread()
+}
+
+// libCall runs fn on the executor's thread.
+func libCall(fn func()) {
+	exec.do(fn)
+}
+
+// dupStderr returns a new file referring to the process' stderr.
+func dupStderr() (*os.File, error) {
+	fd, err := C.dup(2)
+	if fd < 0 {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "/dev/stderr"), nil
+}
+
+// setStderr points the process' stderr at f.
+func setStderr(f *os.File) error {
+	if ret, err := C.dup2(C.int(f.Fd()), 2); ret < 0 {
+		return err
+	}
+	return nil
+}
+
+func initialize() {
+	libCall(func() {
+		for _, opt := range globalOptions {
+			key, value := cString(opt.key), cString(opt.value)
+			C.ao_append_global_option(key, value)
+			cFree(key)
+			cFree(value)
+		}
+
+		C.ao_initialize()
+	})
+}
+
+func shutdown() {
+	libCall(func() { C.ao_shutdown() })
+}
+
+func libDefaultDriver() int {
+	var id C.int
+	libCall(func() { id = C.ao_default_driver_id() })
+	return int(id)
+}
+
+func libDriverID(name string) int {
+	cname := cString(name)
+	defer cFree(cname)
+
+	var id C.int
+	libCall(func() { id = C.ao_driver_id(cname) })
+	return int(id)
+}
+
+func libDriverInfo(id int) (di DriverInfo, ok bool) {
+	libCall(func() {
+		info := C.ao_driver_info(C.int(id))
+		if info != nil {
+			di, ok = makeDriverInfo(id, info), true
+		}
+	})
+	return
+}
+
+func libDriverInfoList() []DriverInfo {
+	var out []DriverInfo
+
+	libCall(func() {
+		var count C.int
+
+		list := C.ao_driver_info_list(&count)
+		if list == nil || count <= 0 {
+			return
+		}
+
+		infos := unsafe.Slice(list, int(count))
+		out = make([]DriverInfo, 0, len(infos))
+
+		for id, info := range infos {
+			if info != nil {
+				out = append(out, makeDriverInfo(id, info))
+			}
+		}
+	})
+
+	return out
+}
+
+// makeDriverInfo copies the given C driver information into Go memory.
+// It calls into libao, so it must be run through libCall().
+func makeDriverInfo(id int, info *C.ao_info) DriverInfo {
+	di := DriverInfo{
+		ID:                  id,
+		Type:                DriverType(info._type),
+		Name:                C.GoString(info.name),
+		ShortName:           C.GoString(info.short_name),
+		Author:              C.GoString(info.author),
+		Comment:             C.GoString(info.comment),
+		PreferredByteFormat: ByteOrder(info.preferred_byte_format),
+		Priority:            int(info.priority),
+	}
+
+	if ext := C.ao_file_extension(C.int(id)); ext != nil {
+		di.Extension = C.GoString(ext)
+	}
+
+	if info.options != nil && info.option_count > 0 {
+		opts := unsafe.Slice(info.options, int(info.option_count))
+		di.Options = make([]string, len(opts))
+
+		for i, opt := range opts {
+			di.Options[i] = C.GoString(opt)
+		}
+	}
+
+	return di
+}
+
+func libOpenLive(driver int, sf *SampleFormat, options map[string]string) (output, error) {
+	args := newOpenArgs(sf, options)
+	defer args.release()
+
+	var dev *C.ao_device
+	var err error
+
+	libCall(func() {
+		dev, err = C.ao_open_live(C.int(driver), &args.format, args.options)
+	})
+
+	if dev == nil {
+		return nil, errnoError(err)
+	}
+
+	return &cOutput{dev}, nil
+}
+
+func libOpenFile(driver int, filename string, overwrite bool, sf *SampleFormat, options map[string]string) (output, error) {
+	args := newOpenArgs(sf, options)
+	args.filename = cString(filename)
+	defer args.release()
+
+	var coverwrite C.int
+	if overwrite {
+		coverwrite = 1
+	}
+
+	var dev *C.ao_device
+	var err error
+
+	libCall(func() {
+		dev, err = C.ao_open_file(
+			C.int(driver),
+			args.filename,
+			coverwrite,
+			&args.format,
+			args.options,
+		)
+	})
+
+	if dev == nil {
+		return nil, errnoError(err)
+	}
+
+	return &cOutput{dev}, nil
+}
+
+// libOpenWriter connects a file driver to w. libao only writes to named
+// files, so it is handed the write end of a pipe through its /dev/fd
+// entry. The pipe is drained into a buffer as soon as libao writes to it,
+// and a separate goroutine passes the buffer on to w. This keeps a slow
+// writer from blocking ao_play() on a full pipe, and with it the executor
+// shared by all devices.
+func libOpenWriter(driver int, w io.Writer, sf *SampleFormat, options map[string]string) (output, error) {
+	if runtime.GOOS == "windows" {
+		return nil, ErrUnsupported
+	}
+
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+
+	out, err := libOpenFile(driver, fmt.Sprintf("/dev/fd/%d", pw.Fd()), true, sf, options)
+
+	// libao opened its own descriptor for the pipe, if it succeeded.
+	pw.Close()
+
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	po := &pipeOutput{
+		out:  out,
+		r:    r,
+		done: make(chan struct{}),
+	}
+
+	po.cond = sync.NewCond(&po.mu)
+	go po.read(r)
+	go po.write(w)
+	return po, nil
+}
+
+// maxPipeBuffer is the number of bytes a pipeOutput buffers for its
+// writer before play() waits for it to catch up.
+const maxPipeBuffer = 1 << 20
+
+// pipeOutput is a libao file output whose data is piped into an io.Writer.
+type pipeOutput struct {
+	out  output
+	r    *os.File      // Read end of the pipe.
+	done chan struct{} // Closed when writing has finished.
+	mu   sync.Mutex
+	cond *sync.Cond // Signalled when any of the fields below change.
+	buf  []byte     // Data read from the pipe, but not yet written.
+	eof  bool       // Has libao closed its end of the pipe?
+	err  error      // First error returned by the writer.
+}
+
+// read moves the pipe's contents into the buffer until libao closes it,
+// or close() interrupts it. It never waits for the writer, so libao never
+// blocks on a full pipe.
+func (o *pipeOutput) read(r *os.File) {
+	defer r.Close()
+
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			o.drain(r, chunk)
+			return
+		}
+
+		o.store(chunk[:n], err != nil)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// drain moves what is left in the pipe into the buffer, without waiting
+// for EOF. libao does not open the pipe close-on-exec, so a child process
+// started while the device was open may hold on to its end of the pipe.
+// EOF then does not arrive until that process exits.
+func (o *pipeOutput) drain(r *os.File, chunk []byte) {
+	defer o.store(nil, true)
+
+	rc, err := r.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	// The pipe is non-blocking; read until it is empty.
+	rc.Control(func(fd uintptr) {
+		for {
+			n := C.read(C.int(fd), unsafe.Pointer(&chunk[0]), C.size_t(len(chunk)))
+			if n <= 0 {
+				return
+			}
+			o.store(chunk[:n], false)
+		}
+	})
+}
+
+// store appends p to the buffer. Once the writer has failed, p is discarded.
+func (o *pipeOutput) store(p []byte, eof bool) {
+	o.mu.Lock()
+	if o.err == nil {
+		o.buf = append(o.buf, p...)
+	}
+	o.eof = eof
+	o.cond.Broadcast()
+	o.mu.Unlock()
+}
+
+// write passes buffered data on to w, until the pipe has been closed
+// and the buffer is empty, or w fails.
+func (o *pipeOutput) write(w io.Writer) {
+	defer close(o.done)
+
+	for {
+		o.mu.Lock()
+		for len(o.buf) == 0 && !o.eof {
+			o.cond.Wait()
+		}
+
+		p := o.buf
+		o.buf = nil
+		o.mu.Unlock()
+
+		if len(p) == 0 {
+			return
+		}
+
+		if _, err := w.Write(p); err != nil {
+			o.mu.Lock()
+			o.err = err
+			o.cond.Broadcast()
+			o.mu.Unlock()
+			return
+		}
+
+		o.mu.Lock()
+		o.cond.Broadcast()
+		o.mu.Unlock()
+	}
+}
+
+// wait blocks until the buffer has room, and returns the error returned
+// by the writer, if any.
+func (o *pipeOutput) wait() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(o.buf) >= maxPipeBuffer && o.err == nil {
+		o.cond.Wait()
+	}
+	return o.err
+}
+
+// failed returns the error returned by the writer, if any.
+func (o *pipeOutput) failed() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+func (o *pipeOutput) play(p []byte) error {
+	// Wait here, rather than in ao_play(), so the executor is not held up.
+	if err := o.wait(); err != nil {
+		return err
+	}
+	return o.out.play(p)
+}
+
+func (o *pipeOutput) close() error {
+	err := o.out.close()
+
+	// libao has flushed its output by now. Take what is left in the pipe,
+	// rather than wait for an EOF which may be held up by a child process.
+	o.r.SetReadDeadline(time.Now())
+	<-o.done
+
+	if werr := o.failed(); werr != nil {
+		err = werr
+	}
+
+	return err
+}
+
+// errnoError maps the errno value set by libao to one of the Err*** values.
+func errnoError(err error) error {
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		if err == nil {
+			return ErrFail
+		}
+		return err
+	}
+
+	switch errno {
+	case C.AO_ENODRIVER:
+		return ErrNoDriver
+	case C.AO_ENOTFILE:
+		return ErrNotFile
+	case C.AO_ENOTLIVE:
+		return ErrNotLive
+	case C.AO_EBADOPTION:
+		return ErrBadOption
+	case C.AO_EOPENDEVICE:
+		return ErrOpenDevice
+	case C.AO_ENOTSUPP:
+		return ErrUnsupported
+	case C.AO_EOPENFILE:
+		return ErrOpenFile
+	case C.AO_EFILEEXISTS:
+		return ErrFileExists
+	case C.AO_EBADFORMAT:
+		return ErrBadFormat
+	}
+
+	return ErrFail
+}
+
+// cOutput plays audio through a libao device.
+type cOutput struct {
+	ptr *C.ao_device
+}
+
+func (o *cOutput) play(p []byte) error {
+	var ret C.int
+
+	libCall(func() {
+		ret = C.ao_play(
+			o.ptr,
+			(*C.char)(unsafe.Pointer(&p[0])),
+			C.uint_32(len(p)),
+		)
+	})
+
+	if ret <= 0 {
+		return errors.New("playback failed; device should be closed")
+	}
+	return nil
+}
+
+func (o *cOutput) close() error {
+	var ret C.int
+	libCall(func() { ret = C.ao_close(o.ptr) })
+
+	if ret <= 0 {
+		return errors.New("failed to close device correctly")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Library is a reference to the initialized audio library.
+//
+// Several components of a program can each hold their own reference.
+// The library is initialized when the first reference is opened and
+// shut down when the last one is closed. Init() and Shutdown() manage
+// one additional reference, shared by all of their callers.
+type Library struct {
+	once sync.Once
+	held bool // Does the handle hold a reference? Set by Open().
+}
+
+var (
+	libLock   sync.RWMutex // Held for writing while references change.
+	libRefs   int          // Number of open references.
+	libLegacy bool         // Is the reference managed by Init() held?
+
+	devicesLock sync.Mutex
+	devices     = make(map[*handle]struct{}) // Devices which are still open.
+)
+
+// Open returns a new reference to the library, initializing it if this
+// is the first one. Refer to Init() for details on initialization.
+// The reference must be released with Library.Close().
+func Open() *Library {
+	libLock.Lock()
+	defer libLock.Unlock()
+
+	acquire()
+	return &Library{held: true}
+}
+
+// Close releases the reference. If this was the last reference to the
+// library, all devices which are still open are closed and the library
+// is shut down. Returns the first error encountered while closing
+// those devices.
+//
+// Subsequent calls to Close do nothing, as do calls on a Library which
+// was not returned by Open().
+func (l *Library) Close() error {
+	var err error
+
+	l.once.Do(func() {
+		if !l.held {
+			return
+		}
+
+		libLock.Lock()
+		defer libLock.Unlock()
+		err = release()
+	})
+
+	return err
+}
+
+// acquire adds a reference to the library, initializing it if needed.
+// libLock must be held for writing.
+func acquire() {
+	libRefs++
+	if libRefs == 1 {
+		initialize()
+		atomic.StoreUint32(&libInitialized, 1)
+	}
+}
+
+// release removes a reference to the library. When the last reference
+// is removed, open devices are closed and the library is shut down.
+// libLock must be held for writing.
+func release() error {
+	libRefs--
+	if libRefs > 0 {
+		return nil
+	}
+
+	err := closeDevices()
+	atomic.StoreUint32(&libInitialized, 0)
+	shutdown()
+	return err
+}
+
+// initialized returns true if the library is initialized.
+func initialized() bool {
+	return atomic.LoadUint32(&libInitialized) == 1
+}
+
+// track records h as the handle of an open device.
+func track(h *handle) {
+	devicesLock.Lock()
+	devices[h] = struct{}{}
+	devicesLock.Unlock()
+}
+
+// untrack removes h from the open devices.
+func untrack(h *handle) {
+	devicesLock.Lock()
+	delete(devices, h)
+	devicesLock.Unlock()
+}
+
+// closeDevices closes all open devices and returns the first error
+// encountered.
+func closeDevices() error {
+	devicesLock.Lock()
+	list := make([]*handle, 0, len(devices))
+	for h := range devices {
+		list = append(list, h)
+	}
+	devicesLock.Unlock()
+
+	var err error
+	for _, h := range list {
+		if cerr := h.close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
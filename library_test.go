@@ -0,0 +1,93 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLibrary(t *testing.T) {
+	a := Open()
+	b := Open()
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The library must survive the first reference being released,
+	// as well as a redundant close of that reference.
+	a.Close()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := OpenLive(driver, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Init and Shutdown hold a reference of their own.
+	Init()
+	Shutdown()
+
+	if err = dev.Play(make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = dev.Play(make([]byte, 64)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("device left open after shutdown: have %v, want %v", err, ErrClosed)
+	}
+
+	if err = dev.Close(); err != nil {
+		t.Fatalf("close after shutdown: %v", err)
+	}
+}
+
+func TestLibraryZero(t *testing.T) {
+	// A Library which holds no reference must not release one.
+	var zero Library
+	if err := zero.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lib := Open()
+	defer lib.Close()
+
+	if _, err := DriverByName(driverName); err != nil {
+		t.Fatalf("library not initialized after closing a zero Library: %v", err)
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	if _, err := DriverByName(driverName); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("DriverByName: have %v, want %v", err, ErrNotInitialized)
+	}
+
+	if _, err := DefaultDriver(); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("DefaultDriver: have %v, want %v", err, ErrNotInitialized)
+	}
+
+	if _, err := DriverInfoByID(0); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("DriverInfoByID: have %v, want %v", err, ErrNotInitialized)
+	}
+
+	if _, err := OpenLive(0, format, nil); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("OpenLive: have %v, want %v", err, ErrNotInitialized)
+	}
+
+	if _, err := OpenFile(0, "out.wav", true, format, nil); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("OpenFile: have %v, want %v", err, ErrNotInitialized)
+	}
+
+	if len(Drivers()) > 0 {
+		t.Fatal("Drivers returned drivers while not initialized")
+	}
+}
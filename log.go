@@ -0,0 +1,134 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// libao reports diagnostics by writing to stderr. While a logger is set,
+// the process' stderr is redirected into a pipe. Its output is read back
+// line by line and sent to the logger. The original stderr is kept, so
+// output can be restored when the logger is removed.
+
+var (
+	loggerLock sync.Mutex
+	logger     *slog.Logger
+	logWriter  *os.File // Write end of the pipe; created once.
+	stderr     *os.File // The original stderr; saved once.
+)
+
+// SetLogger routes the diagnostic output of libao to l, instead of
+// stderr. Each line is logged as a separate message. Lines marked as
+// errors, warnings or debug output are logged at the matching level;
+// the rest at slog.LevelInfo. Use SetVerbosity() to control how much
+// libao reports.
+//
+// The logger also receives the package's own warnings; e.g. about devices
+// which were never closed. Those are dropped while no logger is set.
+//
+// Passing nil restores output to stderr. Without libao, only the package's
+// own warnings are logged.
+//
+// The redirection applies to the whole process: anything else written to
+// stderr while a logger is set ends up with the logger too. That includes
+// the output of a panic, which may be lost if the program exits before it
+// has been read. To keep it, call debug.SetCrashOutput(os.Stderr, ...)
+// before the first call to SetLogger.
+func SetLogger(l *slog.Logger) error {
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+
+	switch {
+	case l != nil && logger == nil:
+		if err := redirectStderr(); err != nil {
+			return err
+		}
+	case l == nil && logger != nil:
+		if err := restoreStderr(); err != nil {
+			return err
+		}
+	}
+
+	logger = l
+	return nil
+}
+
+// redirectStderr points stderr at the log pipe, creating the pipe if it
+// does not exist yet. loggerLock must be held.
+func redirectStderr() error {
+	if logWriter == nil {
+		saved, err := dupStderr()
+		if saved == nil {
+			return err
+		}
+
+		r, w, err := os.Pipe()
+		if err != nil {
+			saved.Close()
+			return err
+		}
+
+		stderr, logWriter = saved, w
+		go readLog(r)
+	}
+
+	return setStderr(logWriter)
+}
+
+// restoreStderr points stderr back at the original. loggerLock must be held.
+func restoreStderr() error {
+	if stderr == nil {
+		return nil
+	}
+
+	return setStderr(stderr)
+}
+
+// currentLogger returns the logger set with SetLogger(), or nil.
+func currentLogger() *slog.Logger {
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+	return logger
+}
+
+// readLog sends the lines read from r to the current logger. Lines which
+// arrive after the logger has been removed go to the original stderr.
+func readLog(r *os.File) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		l := currentLogger()
+		if l == nil {
+			stderr.WriteString(line + "\n")
+			continue
+		}
+
+		l.Log(context.Background(), logLevel(line), line, "source", "libao")
+	}
+}
+
+// logLevel derives the level of a line of libao output from its marker;
+// e.g.: "ao_alsa ERROR: Unable to open device".
+func logLevel(line string) slog.Level {
+	switch {
+	case strings.Contains(line, "ERROR"):
+		return slog.LevelError
+	case strings.Contains(line, "WARNING"):
+		return slog.LevelWarn
+	case strings.Contains(line, "debug"):
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,65 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+//go:build cgo
+
+package ao
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var logs syncBuffer
+	handler := slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	if err := SetLogger(slog.New(handler)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetLogger(nil)
+
+	if err := SetVerbosity(VerbosityDebug); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetVerbosity(VerbosityNormal)
+
+	Init()
+	defer Shutdown()
+
+	if err := SetGlobalOption("debug", ""); !errors.Is(err, ErrInitialized) {
+		t.Errorf("SetGlobalOption after Init: have %v, want %v", err, ErrInitialized)
+	}
+
+	// Anything written to fd 2 is routed to the logger, the way libao's
+	// output is. os.Stderr refers to fd 2.
+	lines := map[string]string{
+		"ao_test ERROR: unable to open device": "level=ERROR",
+		"ao_test WARNING: buffer underrun":     "level=WARN",
+		"ao_test: debug output":                "level=DEBUG",
+		"ao_test: device opened":               "level=INFO",
+	}
+
+	for line := range lines {
+		os.Stderr.WriteString(line + "\n")
+	}
+
+	// The log is read asynchronously.
+	for i := 0; i < 100 && strings.Count(logs.String(), "ao_test") < len(lines); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for line, level := range lines {
+		want := fmt.Sprintf("%s msg=%q source=libao", level, line)
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, logs.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channel identifies a speaker location in a channel matrix.
+// Refer to the SampleFormat documentation for a description of each.
+type Channel int
+
+// Known channel locations.
+const (
+	ChannelInvalid Channel = iota
+	ChannelL               // Left
+	ChannelR               // Right
+	ChannelC               // Center
+	ChannelM               // Monophonic
+	ChannelCL              // Left of Center
+	ChannelCR              // Right of Center
+	ChannelBL              // Back Left
+	ChannelBR              // Back Right
+	ChannelBC              // Back Center
+	ChannelSL              // Side Left
+	ChannelSR              // Side Right
+	ChannelLFE             // Low Frequency Effect
+	ChannelX               // Unused
+	ChannelA1              // Auxiliary 1
+	ChannelA2              // Auxiliary 2
+	ChannelA3              // Auxiliary 3
+	ChannelA4              // Auxiliary 4
+)
+
+// channelNames maps channels to their mnemonics.
+var channelNames = [...]string{
+	ChannelL:   "L",
+	ChannelR:   "R",
+	ChannelC:   "C",
+	ChannelM:   "M",
+	ChannelCL:  "CL",
+	ChannelCR:  "CR",
+	ChannelBL:  "BL",
+	ChannelBR:  "BR",
+	ChannelBC:  "BC",
+	ChannelSL:  "SL",
+	ChannelSR:  "SR",
+	ChannelLFE: "LFE",
+	ChannelX:   "X",
+	ChannelA1:  "A1",
+	ChannelA2:  "A2",
+	ChannelA3:  "A3",
+	ChannelA4:  "A4",
+}
+
+// ParseChannel returns the channel for the given mnemonic.
+func ParseChannel(s string) (Channel, error) {
+	for c, name := range channelNames {
+		if len(name) > 0 && name == s {
+			return Channel(c), nil
+		}
+	}
+	return ChannelInvalid, fmt.Errorf("unknown channel %q: %w", s, ErrBadFormat)
+}
+
+// String returns the channel's mnemonic.
+func (c Channel) String() string {
+	if c > ChannelInvalid && int(c) < len(channelNames) {
+		return channelNames[c]
+	}
+	return fmt.Sprintf("Channel(%d)", int(c))
+}
+
+// Matrix defines the speaker location of each channel in interleaved
+// sample data. Its string form is the channel matrix accepted by
+// SampleFormat.Matrix.
+type Matrix []Channel
+
+// ParseMatrix parses a comma separated list of channel mnemonics,
+// such as "L,R,C,LFE,BR,BL". An empty string yields an empty matrix.
+func ParseMatrix(s string) (Matrix, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	fields := strings.Split(s, ",")
+	m := make(Matrix, len(fields))
+
+	for i, f := range fields {
+		c, err := ParseChannel(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		m[i] = c
+	}
+
+	return m, m.Validate(len(m))
+}
+
+// String returns the matrix as a comma separated list of mnemonics.
+func (m Matrix) String() string {
+	names := make([]string, len(m))
+	for i, c := range m {
+		names[i] = c.String()
+	}
+	return strings.Join(names, ",")
+}
+
+// Index returns the position of channel c in the matrix, or -1 if it
+// is not present.
+func (m Matrix) Index(c Channel) int {
+	for i, v := range m {
+		if v == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate ensures the matrix holds the given number of channels, each
+// of them known, and that no speaker location other than X is used
+// more than once.
+func (m Matrix) Validate(channels int) error {
+	if len(m) != channels {
+		return fmt.Errorf("matrix %q defines %d channels, want %d: %w",
+			m.String(), len(m), channels, ErrBadFormat)
+	}
+
+	var seen [len(channelNames)]bool
+
+	for _, c := range m {
+		if c <= ChannelInvalid || int(c) >= len(channelNames) {
+			return fmt.Errorf("matrix %q: unknown channel %v: %w", m.String(), c, ErrBadFormat)
+		}
+
+		if seen[c] && c != ChannelX {
+			return fmt.Errorf("matrix %q: duplicate channel %v: %w", m.String(), c, ErrBadFormat)
+		}
+
+		seen[c] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMatrix(t *testing.T) {
+	for _, s := range []string{
+		MatrixDefault,
+		MatrixQuadraphonic,
+		Matrix51,
+		Matrix71,
+		Matrix51Vorbis,
+		Matrix71Vorbis,
+		MatrixAIFF,
+		"M",
+		"X,X,A1,A2,A3,A4",
+	} {
+		m, err := ParseMatrix(s)
+		if err != nil {
+			t.Errorf("ParseMatrix(%q): %v", s, err)
+			continue
+		}
+
+		if m.String() != s {
+			t.Errorf("ParseMatrix(%q): String() returns %q", s, m.String())
+		}
+	}
+
+	for _, s := range []string{"L,RC", "L,L", "L,,R", "l,r"} {
+		if _, err := ParseMatrix(s); !errors.Is(err, ErrBadFormat) {
+			t.Errorf("ParseMatrix(%q): want ErrBadFormat, have %v", s, err)
+		}
+	}
+
+	m, _ := ParseMatrix(Matrix51)
+	if err := m.Validate(2); !errors.Is(err, ErrBadFormat) {
+		t.Errorf("Validate: want ErrBadFormat, have %v", err)
+	}
+
+	if m.Index(ChannelLFE) != 3 || m.Index(ChannelSL) != -1 {
+		t.Errorf("Index returns unexpected positions")
+	}
+}
+
+func TestOpenBadMatrix(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName(driverName)
+	if err != nil {
+		t.Errorf("No driver named %q", driverName)
+		return
+	}
+
+	sf := *format
+	sf.Matrix = Matrix51
+
+	if _, err = OpenLive(driver, &sf, nil); !errors.Is(err, ErrBadFormat) {
+		t.Errorf("OpenLive: want ErrBadFormat, have %v", err)
+	}
+}
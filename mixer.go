@@ -0,0 +1,290 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+)
+
+// clipThreshold defines the level above which the mixer starts to
+// gradually compress the output to avoid hard clipping.
+const clipThreshold = 0.9
+
+// Mixer sums any number of input streams into a single output device.
+// Each input has its own gain and pan settings. Mixing happens in
+// floating point; the result is soft-limited to the range [-1, 1] and
+// written in the device's sample format.
+//
+// Mixer implements Stream. When no inputs are attached, it yields silence.
+// An input which returns no samples without an error is silent for that
+// block. Inputs are removed automatically once they return an error,
+// such as io.EOF.
+//
+// Inputs are read without holding the mixer's lock, so a slow input does
+// not hold up calls to Add, Remove, SetGain or SetPan.
+type Mixer struct {
+	dev    *Device
+	mu     sync.Mutex // Guards inputs and their settings.
+	inputs []*MixerInput
+	rmu    sync.Mutex    // Serializes ReadSamples.
+	sum    []float32     // Mixing buffer; guarded by rmu.
+	active []*MixerInput // Inputs being read; guarded by rmu.
+}
+
+// MixerInput represents a single stream attached to a Mixer.
+type MixerInput struct {
+	m        *Mixer
+	s        Stream
+	channels int       // Number of channels in s.
+	gain     float64   // Guarded by m.mu.
+	pan      float64   // Guarded by m.mu.
+	gains    []float32 // Gain matrix for gain and pan; guarded by m.mu.
+	removed  bool      // Has the input been removed? Guarded by m.mu.
+	buf      []float32 // Samples read for the current block; guarded by m.rmu.
+	n        int       // Number of samples in buf.
+	err      error     // Error returned by the last read.
+}
+
+// NewMixer creates a mixer which writes to the given device.
+func NewMixer(dev *Device) *Mixer {
+	return &Mixer{dev: dev}
+}
+
+// Add attaches a stream to the mixer.
+//
+// The gain is a linear amplification factor, where 1 leaves the signal
+// unchanged. Pan ranges from -1 (left) through 0 (center) to 1 (right).
+// It only applies to stereo output devices.
+func (m *Mixer) Add(s Stream, gain, pan float64) *MixerInput {
+	in := &MixerInput{
+		m:        m,
+		s:        s,
+		channels: s.Channels(),
+		gain:     gain,
+		pan:      clamp(pan, -1, 1),
+	}
+
+	in.gains = panGains(in.channels, m.Channels(), in.gain, in.pan)
+
+	m.mu.Lock()
+	m.inputs = append(m.inputs, in)
+	m.mu.Unlock()
+
+	return in
+}
+
+// SetGain changes the input's gain.
+func (in *MixerInput) SetGain(gain float64) {
+	in.m.mu.Lock()
+	in.gain = gain
+	in.updateGains()
+	in.m.mu.Unlock()
+}
+
+// SetPan changes the input's pan position.
+func (in *MixerInput) SetPan(pan float64) {
+	in.m.mu.Lock()
+	in.pan = clamp(pan, -1, 1)
+	in.updateGains()
+	in.m.mu.Unlock()
+}
+
+// updateGains recomputes the gain matrix. The caller must hold m.mu.
+func (in *MixerInput) updateGains() {
+	in.gains = panGains(in.channels, in.m.Channels(), in.gain, in.pan)
+}
+
+// Remove detaches the input from its mixer.
+func (in *MixerInput) Remove() {
+	in.m.mu.Lock()
+	in.m.remove(in)
+	in.m.mu.Unlock()
+}
+
+// remove detaches the given input. The caller must hold m.mu.
+func (m *Mixer) remove(in *MixerInput) {
+	in.removed = true
+
+	for i, v := range m.inputs {
+		if v == in {
+			m.inputs = append(m.inputs[:i], m.inputs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Channels returns the number of output channels.
+func (m *Mixer) Channels() int {
+	return m.dev.format.Channels
+}
+
+// ReadSamples mixes the next len(p) samples of all inputs into p.
+// It never returns an error.
+func (m *Mixer) ReadSamples(p []float32) (int, error) {
+	m.rmu.Lock()
+	defer m.rmu.Unlock()
+
+	out := m.Channels()
+	frames := len(p) / out
+	p = p[:frames*out]
+
+	if cap(m.sum) < len(p) {
+		m.sum = make([]float32, len(p))
+	}
+
+	sum := m.sum[:len(p)]
+	for i := range sum {
+		sum[i] = 0
+	}
+
+	m.mu.Lock()
+	m.active = append(m.active[:0], m.inputs...)
+	m.mu.Unlock()
+
+	for _, in := range m.active {
+		in.read(frames)
+	}
+
+	m.mu.Lock()
+	for i, in := range m.active {
+		if !in.removed {
+			in.mix(sum, out)
+
+			if in.err != nil {
+				m.remove(in)
+			}
+		}
+		m.active[i] = nil
+	}
+	m.mu.Unlock()
+
+	for i, v := range sum {
+		p[i] = softClip(v)
+	}
+
+	return len(p), nil
+}
+
+// Run plays the mixed output on the device until ctx is done.
+// It returns ctx.Err() or a playback error.
+func (m *Mixer) Run(ctx context.Context) error {
+	return m.dev.PlayStream(ctx, m)
+}
+
+// read reads the next frames of the input into its buffer.
+// Samples the input could not provide are silent.
+func (in *MixerInput) read(frames int) {
+	if in.channels <= 0 {
+		in.n, in.err = 0, io.EOF
+		return
+	}
+
+	need := frames * in.channels
+	if cap(in.buf) < need {
+		in.buf = make([]float32, need)
+	}
+
+	buf := in.buf[:need]
+	in.n, in.err = readFull(in.s, buf)
+
+	for i := in.n; i < need; i++ {
+		buf[i] = 0
+	}
+}
+
+// mix adds the samples read by the input to sum, which holds out channels.
+// Inputs without channels add nothing. The caller must hold m.mu.
+func (in *MixerInput) mix(sum []float32, out int) {
+	ch := in.channels
+	if ch <= 0 {
+		return
+	}
+
+	buf := in.buf[:in.n]
+	gains := in.gains
+
+	for f := 0; f < len(buf)/ch; f++ {
+		src := buf[f*ch : f*ch+ch]
+		dst := sum[f*out : f*out+out]
+
+		for o := range dst {
+			for i, v := range src {
+				dst[o] += v * gains[o*ch+i]
+			}
+		}
+	}
+}
+
+// readFull reads from s until p is full or an error occurs.
+// It stops early without an error if s returns no samples; the caller
+// treats the rest of p as silence.
+func readFull(s Stream, p []float32) (int, error) {
+	var n int
+
+	for n < len(p) {
+		m, err := s.ReadSamples(p[n:])
+		n += m
+
+		if err != nil || m == 0 {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// panGains returns an out×in matrix of gains, mapping input channels
+// onto output channels with the given gain and pan position.
+func panGains(in, out int, gain, pan float64) []float32 {
+	g := make([]float32, in*out)
+
+	switch {
+	case in == 1 && out == 2:
+		// Constant power panning.
+		angle := (pan + 1) * math.Pi / 4
+		g[0] = float32(gain * math.Cos(angle))
+		g[1] = float32(gain * math.Sin(angle))
+
+	case in == 2 && out == 2:
+		g[0] = float32(gain * math.Min(1, 1-pan))
+		g[3] = float32(gain * math.Min(1, 1+pan))
+
+	case in == 2 && out == 1:
+		g[0] = float32(gain * 0.5)
+		g[1] = float32(gain * 0.5)
+
+	case in == 1:
+		for o := 0; o < out; o++ {
+			g[o] = float32(gain)
+		}
+
+	default:
+		for i := 0; i < in && i < out; i++ {
+			g[i*in+i] = float32(gain)
+		}
+	}
+
+	return g
+}
+
+// softClip passes values below clipThreshold unchanged and smoothly
+// compresses larger values so they never exceed 1.
+func softClip(v float32) float32 {
+	x := math.Abs(float64(v))
+	if x <= clipThreshold {
+		return v
+	}
+
+	const knee = 1 - clipThreshold
+	y := clipThreshold + knee*math.Tanh((x-clipThreshold)/knee)
+	return float32(math.Copysign(y, float64(v)))
+}
+
+// clamp limits v to the range [lo, hi].
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
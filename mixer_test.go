@@ -0,0 +1,185 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+// constStream yields a fixed number of samples of a constant value.
+type constStream struct {
+	channels int
+	value    float32
+	left     int
+}
+
+func (s *constStream) Channels() int { return s.channels }
+
+func (s *constStream) ReadSamples(p []float32) (int, error) {
+	if s.left == 0 {
+		return 0, io.EOF
+	}
+
+	n := len(p)
+	if n > s.left {
+		n = s.left
+	}
+
+	for i := range p[:n] {
+		p[i] = s.value
+	}
+
+	s.left -= n
+	return n, nil
+}
+
+func TestMixer(t *testing.T) {
+	dev := &Device{format: *format}
+	m := NewMixer(dev)
+
+	m.Add(&constStream{channels: 1, value: 0.25, left: 4}, 1, 0)
+	m.Add(&constStream{channels: 2, value: 0.5, left: 4}, 1, 1)
+
+	buf := make([]float32, 8)
+	if n, err := m.ReadSamples(buf); n != len(buf) || err != nil {
+		t.Errorf("ReadSamples: want %d, nil; have %d, %v", len(buf), n, err)
+		return
+	}
+
+	center := 0.25 * math.Cos(math.Pi/4)
+	want := []float64{
+		center, center + 0.5,
+		center, center + 0.5,
+		center, center,
+		center, center,
+	}
+
+	for i, v := range buf {
+		if math.Abs(float64(v)-want[i]) > 1e-6 {
+			t.Errorf("Sample %d: want %f, have %f", i, want[i], v)
+		}
+	}
+
+	// Inputs are removed once they report io.EOF.
+	m.ReadSamples(buf)
+
+	if len(m.inputs) != 0 {
+		t.Errorf("Exhausted inputs were not removed: %d", len(m.inputs))
+	}
+}
+
+// idleStream returns no samples on every other read.
+type idleStream struct {
+	idle bool
+}
+
+func (s *idleStream) Channels() int { return 1 }
+
+func (s *idleStream) ReadSamples(p []float32) (int, error) {
+	s.idle = !s.idle
+	if s.idle {
+		return 0, nil
+	}
+
+	for i := range p {
+		p[i] = 0.5
+	}
+	return len(p), nil
+}
+
+// blockingStream blocks on each read until it is released.
+type blockingStream struct {
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStream) Channels() int { return 2 }
+
+func (s *blockingStream) ReadSamples(p []float32) (int, error) {
+	s.reading <- struct{}{}
+	<-s.release
+	return 0, io.EOF
+}
+
+func TestMixerIdleInput(t *testing.T) {
+	dev := &Device{format: *format}
+	m := NewMixer(dev)
+	m.Add(&idleStream{}, 1, 0)
+
+	buf := make([]float32, 8)
+
+	// The idle block is silent, but the input must remain attached.
+	m.ReadSamples(buf)
+	if len(m.inputs) != 1 || buf[0] != 0 {
+		t.Fatalf("idle block: %d inputs, sample %f", len(m.inputs), buf[0])
+	}
+
+	m.ReadSamples(buf)
+	if buf[0] == 0 {
+		t.Fatal("input was not mixed after an idle block")
+	}
+}
+
+func TestMixerNoChannels(t *testing.T) {
+	dev := &Device{format: *format}
+	m := NewMixer(dev)
+	m.Add(NewPCMStream(bytes.NewReader(make([]byte, 64)), &SampleFormat{}), 1, 0)
+
+	buf := make([]float32, 8)
+	if n, err := m.ReadSamples(buf); n != len(buf) || err != nil {
+		t.Fatalf("ReadSamples: want %d, nil; have %d, %v", len(buf), n, err)
+	}
+
+	if len(m.inputs) != 0 {
+		t.Fatal("input without channels was not removed")
+	}
+}
+
+func TestMixerSlowInput(t *testing.T) {
+	dev := &Device{format: *format}
+	m := NewMixer(dev)
+
+	s := &blockingStream{
+		reading: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	in := m.Add(s, 1, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.ReadSamples(make([]float32, 8))
+	}()
+
+	<-s.reading
+
+	// None of these may wait for the read in progress.
+	other := m.Add(&constStream{channels: 1, value: 1, left: 8}, 1, 0)
+	other.SetGain(0.5)
+	other.SetPan(-1)
+	in.Remove()
+
+	close(s.release)
+	<-done
+
+	if len(m.inputs) != 1 || m.inputs[0] != other {
+		t.Fatalf("have %d inputs, want only the one added during the read", len(m.inputs))
+	}
+}
+
+func TestSoftClip(t *testing.T) {
+	for _, v := range []float32{-100, -1.5, -1, 1, 1.5, 100} {
+		if c := softClip(v); c > 1 || c < -1 || math.Signbit(float64(c)) != math.Signbit(float64(v)) {
+			t.Errorf("softClip(%f): have %f", v, c)
+		}
+	}
+
+	if c := softClip(0.5); c != 0.5 {
+		t.Errorf("softClip(0.5): want 0.5, have %f", c)
+	}
+}
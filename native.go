@@ -0,0 +1,137 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+//go:build !cgo
+
+package ao
+
+import (
+	"io"
+	"os"
+)
+
+// This file implements the driver functions without libao. It is used
+// whenever cgo is not available, so programs can be built without the
+// libao headers. In its place, it registers Go implementations of libao's
+// null, wav, au and raw drivers, with the same semantics.
+
+// goDriverBase is the id of the first driver registered with
+// RegisterDriver(). Without libao, ids start at zero.
+const goDriverBase = 0
+
+func init() {
+	RegisterDriver("null", nullDriver{})
+	RegisterDriver("wav", &encoderDriver{
+		info: DriverInfo{
+			Type:                DriverFile,
+			Name:                "WAV file output",
+			Extension:           "wav",
+			Comment:             "Sends output to a .wav file",
+			PreferredByteFormat: EndianLittle,
+		},
+		encoder: NewWAVEncoder,
+	})
+	RegisterDriver("au", &encoderDriver{
+		info: DriverInfo{
+			Type:                DriverFile,
+			Name:                "AU file output",
+			Extension:           "au",
+			Comment:             "Sends output to a .au file",
+			PreferredByteFormat: EndianBig,
+		},
+		encoder: NewAUEncoder,
+	})
+	RegisterDriver("raw", &encoderDriver{
+		info: DriverInfo{
+			Type:                DriverFile,
+			Name:                "RAW sample output",
+			Extension:           "raw",
+			Comment:             "Writes raw audio samples to a file",
+			PreferredByteFormat: EndianNative,
+		},
+		encoder: NewRawEncoder,
+	})
+}
+
+func initialize() {}
+
+func shutdown() {}
+
+// dupStderr returns nil; without libao, there is no output to redirect.
+func dupStderr() (*os.File, error) {
+	return nil, nil
+}
+
+func setStderr(f *os.File) error {
+	return nil
+}
+
+func libDefaultDriver() int {
+	return -1
+}
+
+func libDriverID(name string) int {
+	return -1
+}
+
+func libDriverInfo(id int) (DriverInfo, bool) {
+	return DriverInfo{}, false
+}
+
+func libDriverInfoList() []DriverInfo {
+	return nil
+}
+
+func libOpenLive(driver int, sf *SampleFormat, options map[string]string) (output, error) {
+	return nil, ErrNoDriver
+}
+
+func libOpenFile(driver int, filename string, overwrite bool, sf *SampleFormat, options map[string]string) (output, error) {
+	return nil, ErrNoDriver
+}
+
+func libOpenWriter(driver int, w io.Writer, sf *SampleFormat, options map[string]string) (output, error) {
+	return nil, ErrNoDriver
+}
+
+// nativeOptions lists the options accepted by the native drivers.
+var nativeOptions = []string{"debug", "verbose", "quiet"}
+
+// nullDriver is a live driver which discards all audio.
+type nullDriver struct{}
+
+func (nullDriver) Info() DriverInfo {
+	return DriverInfo{
+		Type:                DriverLive,
+		Name:                "Null output",
+		Comment:             "This driver does nothing.",
+		PreferredByteFormat: EndianNative,
+		Options:             nativeOptions,
+	}
+}
+
+func (nullDriver) Open(w io.Writer, sf *SampleFormat, options map[string]string) (io.WriteCloser, error) {
+	return nullWriter{}, nil
+}
+
+// nullWriter discards all data written to it.
+type nullWriter struct{}
+
+func (nullWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (nullWriter) Close() error                { return nil }
+
+// encoderDriver is a file driver which writes through an Encoder.
+type encoderDriver struct {
+	info    DriverInfo
+	encoder func(w io.Writer, sf *SampleFormat) (*Encoder, error)
+}
+
+func (d *encoderDriver) Info() DriverInfo {
+	info := d.info
+	info.Options = nativeOptions
+	return info
+}
+
+func (d *encoderDriver) Open(w io.Writer, sf *SampleFormat, options map[string]string) (io.WriteCloser, error) {
+	return d.encoder(w, sf)
+}
@@ -0,0 +1,109 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+//go:build !cgo
+
+package ao
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNativeFileDrivers(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	if _, err := DefaultDriver(); err == nil {
+		t.Errorf("DefaultDriver: want ErrNoDriver")
+	}
+
+	for name, magic := range map[string][]byte{
+		"wav": []byte("RIFF"),
+		"au":  []byte(".snd"),
+		"raw": {1, 2, 3, 4},
+	} {
+		driver, err := DriverByName(name)
+		if err != nil {
+			t.Errorf("No driver named %q", name)
+			continue
+		}
+
+		filename := filepath.Join(t.TempDir(), "test."+name)
+
+		dev, err := OpenFile(driver, filename, false, format, nil)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		dev.Play([]byte{1, 2, 3, 4})
+
+		if err = dev.Close(); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if !bytes.HasPrefix(data, magic) {
+			t.Errorf("%s: want data starting with %x, have %x", name, magic, data)
+		}
+	}
+}
+
+func TestNativeStdout(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName("wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	dev, err := OpenFile(driver, "-", false, format, nil)
+	os.Stdout = stdout
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev.Play([]byte{1, 2, 3, 4})
+
+	if err = dev.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The device must leave stdout open.
+	if _, err = w.Write([]byte{5}); err != nil {
+		t.Fatalf("stdout was closed: %v", err)
+	}
+
+	w.Close()
+	data := <-out
+
+	// A pipe can not seek, so the header holds an unknown size.
+	if !bytes.HasPrefix(data, []byte("RIFF\xff\xff\xff\xff")) || !bytes.HasSuffix(data, []byte{1, 2, 3, 4, 5}) {
+		t.Errorf("unexpected output: %x", data)
+	}
+}
@@ -1,12 +1,40 @@
 // This file is subject to a BSD license.
 // Its contents can be found in the enclosed LICENSE file.
 
+//go:build cgo
+
 package ao
 
+// #include <stdlib.h>
 // #include <ao/ao.h>
 import "C"
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// cAllocs counts the C allocations made by this package which have not
+// yet been released. It exists to allow tests to detect memory leaks,
+// so all C memory must be allocated through cString() or be counted
+// explicitly, as makeOptions() does for the option list.
+var cAllocs int64
+
+// cString copies s into a C string. It must be released with cFree().
+func cString(s string) *C.char {
+	atomic.AddInt64(&cAllocs, 1)
+	return C.CString(s)
+}
+
+// cFree releases a C string allocated by cString(). It is a no-op for nil.
+func cFree(p *C.char) {
+	if p != nil {
+		C.free(unsafe.Pointer(p))
+		atomic.AddInt64(&cAllocs, -1)
+	}
+}
 
 // makeOptions turns the given map into a linked list of ao_option structs.
+// The list must be released with freeOptions().
 func makeOptions(m map[string]string) *C.ao_option {
 	if len(m) == 0 {
 		return nil
@@ -15,11 +43,17 @@ func makeOptions(m map[string]string) *C.ao_option {
 	var opt *C.ao_option
 
 	for k, v := range m {
-		C.ao_append_option(
-			&opt,
-			C.CString(k),
-			C.CString(v),
-		)
+		ck := cString(k)
+		cv := cString(v)
+
+		// ao_append_option copies key and value, so our own copies
+		// can be released right away.
+		if C.ao_append_option(&opt, ck, cv) > 0 {
+			atomic.AddInt64(&cAllocs, 1)
+		}
+
+		cFree(ck)
+		cFree(cv)
 	}
 
 	return opt
@@ -27,5 +61,63 @@ func makeOptions(m map[string]string) *C.ao_option {
 
 // freeOptions frees previously allocated ao_option sets.
 func freeOptions(opt *C.ao_option) {
+	for o := opt; o != nil; o = o.next {
+		atomic.AddInt64(&cAllocs, -1)
+	}
+
 	C.ao_free_options(opt)
 }
+
+// openArgs holds the C equivalents of the arguments passed to
+// ao_open_live() and ao_open_file(). All C memory it refers to is owned
+// by the openArgs value and is released by a call to release().
+type openArgs struct {
+	format   C.ao_sample_format
+	options  *C.ao_option
+	filename *C.char
+}
+
+// newOpenArgs converts the given arguments to their C equivalents.
+// The filename is left nil; it is only needed by ao_open_file().
+func newOpenArgs(sf *SampleFormat, options map[string]string) *openArgs {
+	return &openArgs{
+		format:  sf.toC(),
+		options: makeOptions(options),
+	}
+}
+
+// release frees all C memory held by the arguments.
+// It is safe to call this more than once.
+func (a *openArgs) release() {
+	cFree(a.format.matrix)
+	cFree(a.filename)
+	freeOptions(a.options)
+
+	a.format.matrix = nil
+	a.filename = nil
+	a.options = nil
+}
+
+// toC converts the sample format to its C equivalent.
+// A non-empty matrix is allocated in C memory and must be released
+// with cFree().
+func (sf *SampleFormat) toC() C.ao_sample_format {
+	csf := C.ao_sample_format{
+		bits:        C.int(sf.Bits),
+		rate:        C.int(sf.Rate),
+		channels:    C.int(sf.Channels),
+		byte_format: C.int(sf.ByteOrder),
+	}
+
+	// Matrix should be explicitely set to NULL if the string is empty.
+	// A zero-length string is not considered valid.
+	if len(sf.Matrix) > 0 {
+		csf.matrix = cString(sf.Matrix)
+	}
+
+	if csf.byte_format == 0 {
+		csf.byte_format = C.AO_FMT_NATIVE
+	}
+
+	return csf
+}
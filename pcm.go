@@ -0,0 +1,79 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"math"
+	"unsafe"
+)
+
+// nativeBigEndian is true if the host stores integers in big-endian order.
+var nativeBigEndian = func() bool {
+	v := uint16(1)
+	return (*[2]byte)(unsafe.Pointer(&v))[0] == 0
+}()
+
+// isBig returns true if the byte order denotes big-endian data.
+// EndianNative and the zero value resolve to the host byte order.
+func (bo ByteOrder) isBig() bool {
+	switch bo {
+	case EndianBig:
+		return true
+	case EndianLittle:
+		return false
+	}
+	return nativeBigEndian
+}
+
+// sampleSize returns the number of bytes used to store a sample
+// of the given bit depth.
+func sampleSize(bits int) int {
+	return (bits + 7) / 8
+}
+
+// putSample stores the full scale 32-bit sample v in b, truncated
+// to the given bit depth. b must hold at least sampleSize(bits) bytes.
+func putSample(b []byte, v int32, bits int, big bool) {
+	size := sampleSize(bits)
+	u := uint32(v) >> uint(32-size*8)
+
+	for i := 0; i < size; i++ {
+		shift := uint(i * 8)
+		if big {
+			shift = uint((size - 1 - i) * 8)
+		}
+		b[i] = byte(u >> shift)
+	}
+}
+
+// getSample reads a sample of the given bit depth from b and returns
+// it as a full scale 32-bit value.
+func getSample(b []byte, bits int, big bool) int32 {
+	size := sampleSize(bits)
+
+	var u uint32
+	for i := 0; i < size; i++ {
+		shift := uint(i * 8)
+		if big {
+			shift = uint((size - 1 - i) * 8)
+		}
+		u |= uint32(b[i]) << shift
+	}
+
+	return int32(u << uint(32-size*8))
+}
+
+// floatToSample converts f to a full scale 32-bit sample.
+// Values outside the range [-1, 1] are clamped.
+func floatToSample(f float64) int32 {
+	switch {
+	case f >= 1:
+		return math.MaxInt32
+	case f <= -1:
+		return -math.MaxInt32
+	case f != f: // NaN
+		return 0
+	}
+	return int32(math.Round(f * math.MaxInt32))
+}
@@ -0,0 +1,58 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestPutSample(t *testing.T) {
+	for _, tc := range []struct {
+		v    int32
+		bits int
+		big  bool
+		want []byte
+	}{
+		{0x12345678, 8, false, []byte{0x12}},
+		{0x12345678, 16, false, []byte{0x34, 0x12}},
+		{0x12345678, 16, true, []byte{0x12, 0x34}},
+		{0x12345678, 24, false, []byte{0x56, 0x34, 0x12}},
+		{0x12345678, 24, true, []byte{0x12, 0x34, 0x56}},
+		{0x12345678, 32, false, []byte{0x78, 0x56, 0x34, 0x12}},
+		{-1 << 16, 16, false, []byte{0xff, 0xff}},
+	} {
+		have := make([]byte, len(tc.want))
+		putSample(have, tc.v, tc.bits, tc.big)
+
+		if !bytes.Equal(have, tc.want) {
+			t.Errorf("putSample(%#x, %d, %v): want %x, have %x", tc.v, tc.bits, tc.big, tc.want, have)
+		}
+
+		back := getSample(have, tc.bits, tc.big)
+		mask := int32(-1) << uint(32-tc.bits)
+		if back != tc.v&mask {
+			t.Errorf("getSample(%x, %d, %v): want %#x, have %#x", have, tc.bits, tc.big, tc.v&mask, back)
+		}
+	}
+}
+
+func TestFloatToSample(t *testing.T) {
+	for _, tc := range []struct {
+		f    float64
+		want int32
+	}{
+		{0, 0},
+		{1, math.MaxInt32},
+		{2, math.MaxInt32},
+		{-1, -math.MaxInt32},
+		{-5, -math.MaxInt32},
+		{math.NaN(), 0},
+	} {
+		if have := floatToSample(tc.f); have != tc.want {
+			t.Errorf("floatToSample(%v): want %d, have %d", tc.f, tc.want, have)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import "context"
+
+// PlayInt16 plays signed 16-bit samples. They are converted to the
+// bit depth and byte order of the device's sample format.
+func (d *Device) PlayInt16(data []int16) error {
+	return d.playSamples(len(data), func(i int) int32 {
+		return int32(data[i]) << 16
+	})
+}
+
+// PlayInt24 plays signed 24-bit samples, each stored in the low 24 bits
+// of an int32. Values outside the 24-bit range are clamped.
+// They are converted to the bit depth and byte order of the device's
+// sample format; a 24-bit device receives packed, 3-byte samples.
+func (d *Device) PlayInt24(data []int32) error {
+	const limit = 1<<23 - 1
+	return d.playSamples(len(data), func(i int) int32 {
+		v := data[i]
+		if v > limit {
+			v = limit
+		} else if v < -limit-1 {
+			v = -limit - 1
+		}
+		return v << 8
+	})
+}
+
+// PlayInt32 plays signed 32-bit samples. They are converted to the
+// bit depth and byte order of the device's sample format.
+func (d *Device) PlayInt32(data []int32) error {
+	return d.playSamples(len(data), func(i int) int32 {
+		return data[i]
+	})
+}
+
+// PlayFloat32 plays floating point samples in the range [-1, 1].
+// Values outside this range are clamped. They are converted to the
+// bit depth and byte order of the device's sample format.
+func (d *Device) PlayFloat32(data []float32) error {
+	return d.playSamples(len(data), func(i int) int32 {
+		return floatToSample(float64(data[i]))
+	})
+}
+
+// PlayFloat64 plays floating point samples in the range [-1, 1].
+// Values outside this range are clamped. They are converted to the
+// bit depth and byte order of the device's sample format.
+func (d *Device) PlayFloat64(data []float64) error {
+	return d.playSamples(len(data), func(i int) int32 {
+		return floatToSample(data[i])
+	})
+}
+
+// playSamples encodes n full scale 32-bit samples, as returned by the
+// sample function, in the device's sample format and plays them.
+func (d *Device) playSamples(n int, sample func(i int) int32) error {
+	if n == 0 {
+		return nil
+	}
+
+	d.lockWrite(context.Background())
+	defer d.unlockWrite()
+
+	bits := d.format.Bits
+	big := d.format.ByteOrder.isBig()
+	size := sampleSize(bits)
+
+	if cap(d.buf) < n*size {
+		d.buf = make([]byte, n*size)
+	}
+
+	buf := d.buf[:n*size]
+	for i := 0; i < n; i++ {
+		putSample(buf[i*size:], sample(i), bits, big)
+	}
+
+	_, err := d.write(context.Background(), buf)
+	return err
+}
@@ -0,0 +1,256 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"fmt"
+	"math"
+)
+
+// Downmix coefficients.
+const (
+	minus3dB = math.Sqrt2 / 2 // -3dB; used to fold a channel into two others.
+	minus6dB = 0.5            // -6dB; used to fold stereo into a single channel.
+)
+
+// Remapper converts interleaved samples from one channel matrix to
+// another. Channels present in both matrices are copied, which takes
+// care of reordering, as in Matrix51Vorbis to Matrix51. Channels missing
+// from the target are folded into their nearest neighbours using the
+// usual downmix coefficients:
+//
+//	C        -> L, R at -3dB
+//	M        -> L, R at 0dB
+//	CL, CR   -> L, R, or C
+//	SL, SR   -> BL, BR, or L, R at -3dB
+//	BL, BR   -> SL, SR, or L, R at -3dB
+//	BC       -> BL and BR, SL and SR, or L and R at -3dB
+//	L, R     -> M or C at -6dB
+//	LFE      -> dropped
+//	X, A1-A4 -> dropped
+//
+// Folding is applied recursively; e.g. SL is folded into L, which in turn
+// is folded into M for a mono target. Output channels are scaled down when
+// the sum of their coefficients exceeds 1, so a full scale input cannot
+// clip. Output channels without any source are silent.
+type Remapper struct {
+	in, out int
+	coef    []float32 // out×in matrix of gains.
+}
+
+// NewRemapper creates a remapper from the src matrix to the dst matrix.
+func NewRemapper(src, dst Matrix) (*Remapper, error) {
+	if len(src) == 0 || len(dst) == 0 {
+		return nil, fmt.Errorf("remap %q to %q: empty matrix: %w", src, dst, ErrBadFormat)
+	}
+
+	if err := src.Validate(len(src)); err != nil {
+		return nil, err
+	}
+
+	if err := dst.Validate(len(dst)); err != nil {
+		return nil, err
+	}
+
+	r := &Remapper{
+		in:   len(src),
+		out:  len(dst),
+		coef: make([]float32, len(src)*len(dst)),
+	}
+
+	row := make([]float64, len(dst))
+	for i, c := range src {
+		for o := range row {
+			row[o] = 0
+		}
+
+		fold(row, dst, c, 1, 0)
+
+		for o, v := range row {
+			r.coef[o*r.in+i] = float32(v)
+		}
+	}
+
+	r.normalize()
+	return r, nil
+}
+
+// fold adds channel c, at the given gain, to the matching output channels
+// in row. Channels absent from dst are folded into their neighbours.
+func fold(row []float64, dst Matrix, c Channel, gain float64, depth int) {
+	if i := dst.Index(c); i > -1 && c != ChannelX {
+		row[i] += gain
+		return
+	}
+
+	// Guard against folding rules which refer back to each other.
+	if depth > 4 {
+		return
+	}
+
+	into := func(g float64, cs ...Channel) {
+		for _, c := range cs {
+			fold(row, dst, c, gain*g, depth+1)
+		}
+	}
+
+	has := func(cs ...Channel) bool {
+		for _, c := range cs {
+			if dst.Index(c) == -1 {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch c {
+	case ChannelC:
+		if has(ChannelM) {
+			into(1, ChannelM)
+		} else {
+			into(minus3dB, ChannelL, ChannelR)
+		}
+
+	case ChannelM:
+		if has(ChannelC) {
+			into(1, ChannelC)
+		} else {
+			into(1, ChannelL, ChannelR)
+		}
+
+	case ChannelL, ChannelR:
+		if has(ChannelM) {
+			into(minus6dB, ChannelM)
+		} else if has(ChannelC) {
+			into(minus6dB, ChannelC)
+		}
+
+	case ChannelCL, ChannelCR:
+		side := ChannelL
+		if c == ChannelCR {
+			side = ChannelR
+		}
+
+		if has(side) {
+			into(1, side)
+		} else {
+			into(1, ChannelC)
+		}
+
+	case ChannelSL, ChannelSR, ChannelBL, ChannelBR:
+		var alt, front Channel
+
+		switch c {
+		case ChannelSL:
+			alt, front = ChannelBL, ChannelL
+		case ChannelSR:
+			alt, front = ChannelBR, ChannelR
+		case ChannelBL:
+			alt, front = ChannelSL, ChannelL
+		case ChannelBR:
+			alt, front = ChannelSR, ChannelR
+		}
+
+		if has(alt) {
+			into(1, alt)
+		} else {
+			into(minus3dB, front)
+		}
+
+	case ChannelBC:
+		if has(ChannelBL, ChannelBR) {
+			into(minus3dB, ChannelBL, ChannelBR)
+		} else if has(ChannelSL, ChannelSR) {
+			into(minus3dB, ChannelSL, ChannelSR)
+		} else {
+			into(minus3dB, ChannelL, ChannelR)
+		}
+	}
+}
+
+// normalize scales down output channels whose coefficients sum to more than 1.
+func (r *Remapper) normalize() {
+	for o := 0; o < r.out; o++ {
+		row := r.coef[o*r.in : o*r.in+r.in]
+
+		var sum float32
+		for _, v := range row {
+			sum += v
+		}
+
+		if sum > 1 {
+			for i := range row {
+				row[i] /= sum
+			}
+		}
+	}
+}
+
+// Coefficient returns the gain with which input channel i contributes
+// to output channel o.
+func (r *Remapper) Coefficient(o, i int) float32 {
+	return r.coef[o*r.in+i]
+}
+
+// Process remaps the interleaved frames in src and writes them to dst.
+// It processes as many frames as fit in both buffers and returns the
+// number of samples written to dst.
+func (r *Remapper) Process(dst, src []float32) int {
+	frames := len(src) / r.in
+	if n := len(dst) / r.out; n < frames {
+		frames = n
+	}
+
+	for f := 0; f < frames; f++ {
+		in := src[f*r.in : f*r.in+r.in]
+		out := dst[f*r.out : f*r.out+r.out]
+
+		for o := range out {
+			var v float32
+			for i, s := range in {
+				v += s * r.coef[o*r.in+i]
+			}
+			out[o] = v
+		}
+	}
+
+	return frames * r.out
+}
+
+// remapStream applies a Remapper to a Stream.
+type remapStream struct {
+	src Stream
+	r   *Remapper
+	buf []float32
+}
+
+// NewRemapStream returns a Stream which remaps the channels of s from
+// the src matrix to the dst matrix.
+func NewRemapStream(s Stream, src, dst Matrix) (Stream, error) {
+	if s.Channels() != len(src) {
+		return nil, fmt.Errorf("stream has %d channels, matrix %q has %d: %w",
+			s.Channels(), src, len(src), ErrBadFormat)
+	}
+
+	r, err := NewRemapper(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remapStream{src: s, r: r}, nil
+}
+
+func (s *remapStream) Channels() int {
+	return s.r.out
+}
+
+func (s *remapStream) ReadSamples(p []float32) (int, error) {
+	need := len(p) / s.r.out * s.r.in
+	if cap(s.buf) < need {
+		s.buf = make([]float32, need)
+	}
+
+	n, err := s.src.ReadSamples(s.buf[:need])
+	return s.r.Process(p, s.buf[:n]), err
+}
@@ -0,0 +1,81 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRemapper(t *testing.T) {
+	for _, tc := range []struct {
+		src, dst string
+		in       []float32
+		want     []float32
+	}{
+		{Matrix51Vorbis, Matrix51,
+			[]float32{1, 2, 3, 4, 5, 6},
+			[]float32{1, 3, 2, 6, 4, 5}},
+		{MatrixDefault, "M",
+			[]float32{1, 0.5},
+			[]float32{0.75}},
+		{"M", MatrixDefault,
+			[]float32{0.5},
+			[]float32{0.5, 0.5}},
+		{Matrix51, MatrixDefault,
+			[]float32{0, 0, 0, 1, 0, 0},
+			[]float32{0, 0}},
+		{Matrix71, Matrix51,
+			[]float32{0, 0, 0, 0, 0, 0, 0.5, 0},
+			[]float32{0, 0, 0, 0, 0, 0.25}},
+	} {
+		src, _ := ParseMatrix(tc.src)
+		dst, _ := ParseMatrix(tc.dst)
+
+		r, err := NewRemapper(src, dst)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		have := make([]float32, len(tc.want))
+		if n := r.Process(have, tc.in); n != len(tc.want) {
+			t.Errorf("%s -> %s: want %d samples, have %d", tc.src, tc.dst, len(tc.want), n)
+			continue
+		}
+
+		for i := range have {
+			if math.Abs(float64(have[i]-tc.want[i])) > 1e-6 {
+				t.Errorf("%s -> %s: want %v, have %v", tc.src, tc.dst, tc.want, have)
+				break
+			}
+		}
+	}
+}
+
+func TestRemapperDownmix(t *testing.T) {
+	src, _ := ParseMatrix(Matrix51)
+	dst, _ := ParseMatrix(MatrixDefault)
+
+	r, err := NewRemapper(src, dst)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// L = L + 0.707 C + 0.707 BL, normalized.
+	sum := 1 + 2*minus3dB
+	want := [][]float64{
+		{1 / sum, 0, minus3dB / sum, 0, 0, minus3dB / sum},
+		{0, 1 / sum, minus3dB / sum, 0, minus3dB / sum, 0},
+	}
+
+	for o, row := range want {
+		for i, w := range row {
+			if c := r.Coefficient(o, i); math.Abs(float64(c)-w) > 1e-6 {
+				t.Errorf("Coefficient(%v, %v): want %f, have %f", dst[o], src[i], w, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// ResampleQuality selects the interpolation method used by a Resampler.
+type ResampleQuality int
+
+// Known resampling qualities.
+const (
+	ResampleLinear ResampleQuality = iota // Linear interpolation; fast, but prone to aliasing.
+	ResampleSinc                          // Windowed sinc interpolation; slower, but much cleaner.
+)
+
+const (
+	sincTaps       = 16  // Number of sinc lobes on either side of a sample.
+	sincResolution = 256 // Number of kernel table entries per input sample.
+	maxEmptyReads  = 100 // Number of empty reads before a source is considered stuck.
+)
+
+// Resampler converts a Stream from one sample rate to another.
+// It keeps the necessary input history between calls to ReadSamples,
+// so the output is continuous regardless of how it is read.
+type Resampler struct {
+	src      Stream
+	channels int
+	from     int64     // Input frames per rate unit.
+	to       int64     // Output frames per rate unit.
+	pos      int64     // Read position in hist, in units of 1/to frames.
+	hist     []float32 // Buffered, interleaved input frames.
+	frames   int       // Number of real input frames in hist.
+	eof      bool      // Has src been exhausted?
+	err      error     // Error which ended src; io.EOF at its regular end.
+	lead     int       // Number of frames needed before the read position.
+	trail    int       // Number of frames needed after the read position.
+	kernel   []float64 // Sinc kernel table; nil for linear interpolation.
+	width    float64   // Half width of the sinc kernel, in input frames.
+	in       []float32 // Scratch buffer for reads from src.
+}
+
+// NewResampler creates a resampler which converts src from the given
+// sample rate to another.
+//
+// Returns an error wrapping ErrBadFormat if either rate is not positive.
+func NewResampler(src Stream, from, to int, quality ResampleQuality) (*Resampler, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("resample %d Hz to %d Hz: %w", from, to, ErrBadFormat)
+	}
+
+	r := &Resampler{
+		src:      src,
+		channels: src.Channels(),
+		from:     int64(from),
+		to:       int64(to),
+		trail:    1,
+	}
+
+	if quality == ResampleSinc {
+		r.makeKernel(math.Min(1, float64(to)/float64(from)))
+	}
+
+	// Pad the history with silence, so the first output samples
+	// have a complete neighbourhood to interpolate from.
+	r.hist = make([]float32, r.lead*r.channels)
+	r.frames = r.lead
+	r.pos = int64(r.lead) * r.to
+	return r, nil
+}
+
+// makeKernel builds a Blackman windowed sinc table with the given cutoff
+// frequency, relative to the input Nyquist frequency.
+func (r *Resampler) makeKernel(cutoff float64) {
+	r.width = math.Ceil(sincTaps / cutoff)
+	r.lead = int(r.width) - 1
+	r.trail = int(r.width)
+	r.kernel = make([]float64, int(r.width)*sincResolution+1)
+
+	for i := range r.kernel {
+		x := float64(i) / sincResolution
+		u := x / r.width
+		window := 0.42 + 0.5*math.Cos(math.Pi*u) + 0.08*math.Cos(2*math.Pi*u)
+		r.kernel[i] = cutoff * sinc(cutoff*x) * window
+	}
+}
+
+// Channels returns the number of channels.
+func (r *Resampler) Channels() int {
+	return r.channels
+}
+
+// ReadSamples reads up to len(p) resampled samples into p.
+// Once the source is exhausted, it returns io.EOF. If the source failed,
+// its error is returned instead; a source which repeatedly returns no
+// samples without an error fails with io.ErrNoProgress.
+func (r *Resampler) ReadSamples(p []float32) (int, error) {
+	ch := r.channels
+	if ch <= 0 {
+		return 0, io.EOF
+	}
+
+	var n int
+	for n+ch <= len(p) {
+		i := int(r.pos / r.to)
+		r.fill(i + r.trail + 1)
+
+		if r.eof && i >= r.frames {
+			break
+		}
+
+		if r.kernel == nil {
+			r.linear(p[n:n+ch], i)
+		} else {
+			r.sinc(p[n:n+ch], i)
+		}
+
+		n += ch
+		r.pos += r.from
+	}
+
+	r.discard()
+
+	if n == 0 && r.eof {
+		return 0, r.err
+	}
+
+	return n, nil
+}
+
+// fill ensures hist holds at least the given number of frames. Once the
+// source is exhausted, it is padded with silence.
+func (r *Resampler) fill(frames int) {
+	ch := r.channels
+	empty := 0
+
+	for len(r.hist)/ch < frames {
+		if r.eof {
+			r.hist = append(r.hist, make([]float32, (frames-len(r.hist)/ch)*ch)...)
+			return
+		}
+
+		if len(r.in) == 0 {
+			r.in = make([]float32, 1024*ch)
+		}
+
+		m, err := r.src.ReadSamples(r.in)
+		r.hist = append(r.hist, r.in[:m]...)
+		r.frames += m / ch
+
+		if err == nil && m == 0 {
+			if empty++; empty >= maxEmptyReads {
+				err = io.ErrNoProgress
+			}
+		}
+
+		if err != nil {
+			// Any error ends the stream; there is no way to resume.
+			r.eof = true
+			r.err = err
+		}
+	}
+}
+
+// discard drops frames from the history which are no longer needed.
+func (r *Resampler) discard() {
+	drop := int(r.pos/r.to) - r.lead
+	if drop <= 0 {
+		return
+	}
+
+	ch := r.channels
+	if drop > len(r.hist)/ch {
+		drop = len(r.hist) / ch
+	}
+
+	r.hist = r.hist[:copy(r.hist, r.hist[drop*ch:])]
+	r.frames -= drop
+	r.pos -= int64(drop) * r.to
+}
+
+// linear computes a single output frame by linear interpolation
+// between the frames at i and i+1.
+func (r *Resampler) linear(out []float32, i int) {
+	ch := r.channels
+	f := float32(r.offset(i))
+	a := r.hist[i*ch:]
+	b := r.hist[(i+1)*ch:]
+
+	for c := range out {
+		out[c] = a[c] + (b[c]-a[c])*f
+	}
+}
+
+// sinc computes a single output frame by convolving the frames around
+// position i with the sinc kernel.
+func (r *Resampler) sinc(out []float32, i int) {
+	ch := r.channels
+
+	for c := range out {
+		out[c] = 0
+	}
+
+	var total float64
+	for j := i - r.lead; j <= i+r.trail; j++ {
+		w := r.weight(r.offset(j))
+		total += w
+
+		frame := r.hist[j*ch : j*ch+ch]
+		for c, v := range frame {
+			out[c] += float32(w) * v
+		}
+	}
+
+	// Normalize, so a constant signal keeps its level.
+	if total != 0 {
+		for c := range out {
+			out[c] = float32(float64(out[c]) / total)
+		}
+	}
+}
+
+// offset returns the distance between the read position and frame i.
+func (r *Resampler) offset(i int) float64 {
+	return float64(r.pos-int64(i)*r.to) / float64(r.to)
+}
+
+// weight returns the kernel value at offset x, interpolated from the table.
+func (r *Resampler) weight(x float64) float64 {
+	x = math.Abs(x) * sincResolution
+	i := int(x)
+	if i >= len(r.kernel)-1 {
+		return 0
+	}
+
+	f := x - float64(i)
+	return r.kernel[i] + (r.kernel[i+1]-r.kernel[i])*f
+}
+
+// sinc returns the normalized sinc function of x.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
@@ -0,0 +1,93 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestResampler(t *testing.T) {
+	for _, q := range []ResampleQuality{ResampleLinear, ResampleSinc} {
+		for _, rates := range [][2]int{{22050, 48000}, {48000, 44100}, {44100, 44100}} {
+			const frames = 4410
+
+			// Read everything at once, and in small, odd chunks.
+			a := readAll(newResampler(t, &constStream{channels: 2, value: 0.5, left: frames * 2}, rates[0], rates[1], q), 1<<20)
+			b := readAll(newResampler(t, &constStream{channels: 2, value: 0.5, left: frames * 2}, rates[0], rates[1], q), 6)
+
+			want := frames * 2 * rates[1] / rates[0]
+			if math.Abs(float64(len(a)-want)) > 4 {
+				t.Errorf("%v %v: want about %d samples, have %d", q, rates, want, len(a))
+			}
+
+			if len(a) != len(b) {
+				t.Errorf("%v %v: chunked read yields %d samples, want %d", q, rates, len(b), len(a))
+				continue
+			}
+
+			for i := range a {
+				if a[i] != b[i] {
+					t.Errorf("%v %v: sample %d differs between chunked and whole reads", q, rates, i)
+					break
+				}
+			}
+
+			// Away from the edges, a constant signal must remain constant.
+			for i := 200; i < len(a)-200; i++ {
+				if math.Abs(float64(a[i])-0.5) > 1e-3 {
+					t.Errorf("%v %v: sample %d: want 0.5, have %f", q, rates, i, a[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestResamplerErrors(t *testing.T) {
+	for _, rates := range [][2]int{{0, 44100}, {44100, 0}, {-1, 44100}} {
+		if _, err := NewResampler(&idleStream{}, rates[0], rates[1], ResampleLinear); !errors.Is(err, ErrBadFormat) {
+			t.Errorf("%v: have %v, want %v", rates, err, ErrBadFormat)
+		}
+	}
+
+	r := newResampler(t, &emptyStream{}, 44100, 48000, ResampleLinear)
+	if _, err := r.ReadSamples(make([]float32, 8)); err != io.ErrNoProgress {
+		t.Errorf("stuck source: have %v, want %v", err, io.ErrNoProgress)
+	}
+}
+
+// emptyStream never returns any samples, nor an error.
+type emptyStream struct{}
+
+func (emptyStream) Channels() int                        { return 1 }
+func (emptyStream) ReadSamples(p []float32) (int, error) { return 0, nil }
+
+// newResampler creates a resampler, failing the test on error.
+func newResampler(t *testing.T, s Stream, from, to int, q ResampleQuality) *Resampler {
+	t.Helper()
+
+	r, err := NewResampler(s, from, to, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// readAll reads s to the end in chunks of the given size.
+func readAll(s Stream, chunk int) []float32 {
+	var out []float32
+	buf := make([]float32, chunk)
+
+	for {
+		n, err := s.ReadSamples(buf)
+		out = append(out, buf[:n]...)
+
+		if err == io.EOF {
+			return out
+		}
+	}
+}
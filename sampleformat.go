@@ -3,8 +3,12 @@
 
 package ao
 
-// #include <ao/ao.h>
-import "C"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // SampleFormat defines the format of audio samples.
 //
@@ -63,7 +67,8 @@ import "C"
 // 'surround'.
 //
 // Refer to the Matrix*** constants for examples of common matrix
-// configurations.
+// configurations. The Matrix type and ParseMatrix() can be used to build
+// and validate matrices programmatically.
 type SampleFormat struct {
 	Matrix    string    // String defining the channel input matrix.
 	Bits      int       // Bits per sample.
@@ -78,27 +83,157 @@ func (sf *SampleFormat) Bitrate() int {
 	return sf.Rate * sf.Bits * sf.Channels
 }
 
-// toC converts the sample format to its C equivalent.
-func (sf *SampleFormat) toC() *C.ao_sample_format {
-	csf := &C.ao_sample_format{
-		bits:        C.int(sf.Bits),
-		rate:        C.int(sf.Rate),
-		channels:    C.int(sf.Channels),
-		byte_format: C.int(sf.ByteOrder),
-		matrix:      C.CString(sf.Matrix),
+// Validate ensures the sample format holds sensible values: a bit depth
+// of 8, 16, 24 or 32, a positive rate and channel count, a known byte
+// order and, if set, a valid matrix matching the number of channels.
+//
+// Returns an error wrapping ErrBadFormat if any of these do not hold.
+func (sf *SampleFormat) Validate() error {
+	switch sf.Bits {
+	case 8, 16, 24, 32:
+	default:
+		return fmt.Errorf("unsupported bit depth %d: %w", sf.Bits, ErrBadFormat)
+	}
+
+	if sf.Rate <= 0 {
+		return fmt.Errorf("invalid sample rate %d: %w", sf.Rate, ErrBadFormat)
+	}
+
+	if sf.Channels <= 0 {
+		return fmt.Errorf("invalid channel count %d: %w", sf.Channels, ErrBadFormat)
+	}
+
+	switch sf.ByteOrder {
+	case 0, EndianLittle, EndianBig, EndianNative:
+	default:
+		return fmt.Errorf("unknown byte order %d: %w", sf.ByteOrder, ErrBadFormat)
 	}
 
-	// Matrix should be explicitely set to NULL if the string is empty.
-	// A zero-length string is not considered valid.
 	if len(sf.Matrix) == 0 {
-		csf.matrix = nil
+		return nil
+	}
+
+	m, err := ParseMatrix(sf.Matrix)
+	if err != nil {
+		return err
+	}
+
+	return m.Validate(sf.Channels)
+}
+
+// FrameSize returns the number of bytes in a single frame; that is
+// one sample for each channel.
+func (sf *SampleFormat) FrameSize() int {
+	return sampleSize(sf.Bits) * sf.Channels
+}
+
+// BytesFor returns the number of bytes needed to hold audio of the
+// given duration. The result is rounded down to whole frames.
+func (sf *SampleFormat) BytesFor(d time.Duration) int {
+	// Split off whole seconds, so long durations do not overflow.
+	rate := int64(sf.Rate)
+	frames := int64(d/time.Second)*rate + int64(d%time.Second)*rate/int64(time.Second)
+	return int(frames) * sf.FrameSize()
+}
+
+// DurationOf returns the playback duration of n bytes of audio.
+func (sf *SampleFormat) DurationOf(n int) time.Duration {
+	size := sf.FrameSize()
+	if size <= 0 || sf.Rate <= 0 {
+		return 0
+	}
+
+	frames := int64(n / size)
+	rate := int64(sf.Rate)
+	return time.Duration(frames/rate)*time.Second +
+		time.Duration(frames%rate*int64(time.Second)/rate)
+}
+
+// String returns the sample format in its compact text form:
+//
+//	s<bits>[le|be]:<rate>:<channels>[:<matrix>]
+//
+// For example: "s16le:44100:2:L,R". The byte order suffix is omitted
+// for EndianNative.
+func (sf *SampleFormat) String() string {
+	var order string
+	switch sf.ByteOrder {
+	case EndianLittle:
+		order = "le"
+	case EndianBig:
+		order = "be"
 	}
 
-	if csf.byte_format == 0 {
-		csf.byte_format = C.AO_FMT_NATIVE
+	s := fmt.Sprintf("s%d%s:%d:%d", sf.Bits, order, sf.Rate, sf.Channels)
+	if len(sf.Matrix) > 0 {
+		s += ":" + sf.Matrix
+	}
+
+	return s
+}
+
+// Set parses the given compact text form into the sample format.
+// Together with String(), this implements flag.Value, so a sample
+// format can be passed as a single command line flag.
+func (sf *SampleFormat) Set(s string) error {
+	v, err := ParseSampleFormat(s)
+	if err != nil {
+		return err
 	}
 
-	return csf
+	*sf = v
+	return nil
+}
+
+// ParseSampleFormat parses a sample format in the compact text form
+// produced by SampleFormat.String(). The byte order suffix may be "le",
+// "be" or "ne", where "ne" or no suffix at all denotes EndianNative.
+// The result is validated.
+func ParseSampleFormat(s string) (SampleFormat, error) {
+	var sf SampleFormat
+
+	fields := strings.SplitN(s, ":", 4)
+	if len(fields) < 3 {
+		return sf, fmt.Errorf("sample format %q: want s<bits>[le|be|ne]:<rate>:<channels>[:<matrix>]: %w",
+			s, ErrBadFormat)
+	}
+
+	kind := fields[0]
+	if !strings.HasPrefix(kind, "s") {
+		return sf, fmt.Errorf("sample format %q: unsupported sample type: %w", s, ErrBadFormat)
+	}
+
+	kind = kind[1:]
+	sf.ByteOrder = EndianNative
+
+	switch {
+	case strings.HasSuffix(kind, "le"):
+		sf.ByteOrder = EndianLittle
+	case strings.HasSuffix(kind, "be"):
+		sf.ByteOrder = EndianBig
+	case strings.HasSuffix(kind, "ne"):
+	default:
+		kind += "ne"
+	}
+
+	var err error
+	if sf.Bits, err = strconv.Atoi(kind[:len(kind)-2]); err != nil {
+		return sf, fmt.Errorf("sample format %q: invalid bit depth: %w", s, ErrBadFormat)
+	}
+
+	if sf.Rate, err = strconv.Atoi(fields[1]); err != nil {
+		return sf, fmt.Errorf("sample format %q: invalid rate: %w", s, ErrBadFormat)
+	}
+
+	if sf.Channels, err = strconv.Atoi(fields[2]); err != nil {
+		return sf, fmt.Errorf("sample format %q: invalid channel count: %w", s, ErrBadFormat)
+	}
+
+	if len(fields) == 4 {
+		sf.Matrix = fields[3]
+	}
+
+	return sf, sf.Validate()
 }
 
 // Common examples of channel orderings.
@@ -117,15 +252,38 @@ const (
 	Matrix71           = "L,R,C,LFE,BR,BL,SL,SR" // Channel order of a 7.1 WAV or FLAC file
 	Matrix51Vorbis     = "L,C,R,BR,BL,LFE"       // Channel order of a six channel (5.1) Vorbis I file
 	Matrix71Vorbis     = "L,C,R,BR,BL,SL,SR,LFE" // Channel order of an eight channel (7.1) Vorbis file
-	MatrixAIFF         = "L,CL,C,R,RC,BC"        // Channel order of a six channel AIFF[-C] file
+	MatrixAIFF         = "L,CL,C,R,CR,BC"        // Channel order of a six channel AIFF[-C] file
 )
 
+// Common sample formats. Each call returns a new copy, which the caller
+// is free to modify.
+
+// FormatCD returns the sample format of Compact Disc audio.
+func FormatCD() SampleFormat {
+	return SampleFormat{Bits: 16, Rate: 44100, Channels: 2, ByteOrder: EndianNative, Matrix: MatrixDefault}
+}
+
+// FormatDAT returns the sample format of Digital Audio Tape.
+func FormatDAT() SampleFormat {
+	return SampleFormat{Bits: 16, Rate: 48000, Channels: 2, ByteOrder: EndianNative, Matrix: MatrixDefault}
+}
+
+// FormatTelephony returns the sample format of narrowband telephony.
+func FormatTelephony() SampleFormat {
+	return SampleFormat{Bits: 16, Rate: 8000, Channels: 1, ByteOrder: EndianNative, Matrix: "M"}
+}
+
+// FormatStudio returns the 32-bit integer equivalent of 48k float audio.
+func FormatStudio() SampleFormat {
+	return SampleFormat{Bits: 32, Rate: 48000, Channels: 2, ByteOrder: EndianNative, Matrix: MatrixDefault}
+}
+
 // ByteOrder defines endianess for sample data.
 type ByteOrder int
 
 // Known byte orders.
 const (
-	EndianLittle ByteOrder = C.AO_FMT_LITTLE // Samples are in little-endian order.
-	EndianBig    ByteOrder = C.AO_FMT_BIG    // Samples are in big-endian order
-	EndianNative ByteOrder = C.AO_FMT_NATIVE // Samples are in the native ordering of the computer.
+	EndianLittle ByteOrder = 1 // Samples are in little-endian order.
+	EndianBig    ByteOrder = 2 // Samples are in big-endian order
+	EndianNative ByteOrder = 4 // Samples are in the native ordering of the computer.
 )
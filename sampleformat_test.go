@@ -0,0 +1,96 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseSampleFormat(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want SampleFormat
+		out  string
+	}{
+		{"s16le:44100:2:L,R", SampleFormat{Bits: 16, Rate: 44100, Channels: 2, ByteOrder: EndianLittle, Matrix: "L,R"}, "s16le:44100:2:L,R"},
+		{"s24be:48000:6", SampleFormat{Bits: 24, Rate: 48000, Channels: 6, ByteOrder: EndianBig}, "s24be:48000:6"},
+		{"s8:8000:1", SampleFormat{Bits: 8, Rate: 8000, Channels: 1, ByteOrder: EndianNative}, "s8:8000:1"},
+		{"s32ne:96000:2", SampleFormat{Bits: 32, Rate: 96000, Channels: 2, ByteOrder: EndianNative}, "s32:96000:2"},
+	} {
+		have, err := ParseSampleFormat(tc.in)
+		if err != nil {
+			t.Errorf("ParseSampleFormat(%q): %v", tc.in, err)
+			continue
+		}
+
+		if have != tc.want {
+			t.Errorf("ParseSampleFormat(%q): want %+v, have %+v", tc.in, tc.want, have)
+		}
+
+		if have.String() != tc.out {
+			t.Errorf("ParseSampleFormat(%q): String() returns %q, want %q", tc.in, have.String(), tc.out)
+		}
+	}
+
+	for _, s := range []string{"", "s16le", "u8:8000:1", "s12:8000:1", "s16:0:2", "s16:8000:0", "s16:8000:1:L,R", "sxle:1:1"} {
+		if _, err := ParseSampleFormat(s); !errors.Is(err, ErrBadFormat) {
+			t.Errorf("ParseSampleFormat(%q): want ErrBadFormat, have %v", s, err)
+		}
+	}
+}
+
+func TestSampleFormatFlag(t *testing.T) {
+	sf := FormatCD()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&sf, "format", "sample format")
+
+	if err := fs.Parse([]string{"-format", "s16be:22050:1:M"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if sf.Rate != 22050 || sf.Channels != 1 || sf.ByteOrder != EndianBig || sf.Matrix != "M" {
+		t.Errorf("Unexpected format: %+v", sf)
+	}
+}
+
+func TestSampleFormatSizes(t *testing.T) {
+	for _, sf := range []SampleFormat{FormatCD(), FormatDAT(), FormatTelephony(), FormatStudio()} {
+		if err := sf.Validate(); err != nil {
+			t.Errorf("%v: %v", &sf, err)
+		}
+	}
+
+	sf := FormatCD()
+	if sf.FrameSize() != 4 {
+		t.Errorf("FrameSize: want 4, have %d", sf.FrameSize())
+	}
+
+	if n := sf.BytesFor(time.Second); n != 44100*4 {
+		t.Errorf("BytesFor(1s): want %d, have %d", 44100*4, n)
+	}
+
+	if d := sf.DurationOf(44100 * 2); d != 500*time.Millisecond {
+		t.Errorf("DurationOf: want 500ms, have %v", d)
+	}
+
+	// Long durations must not overflow. Their size only fits a 64-bit int.
+	if strconv.IntSize == 64 {
+		long := 100*time.Hour + 250*time.Millisecond
+		want := (int64(100*3600*44100) + 44100/4) * 4
+
+		if n := sf.BytesFor(long); int64(n) != want {
+			t.Errorf("BytesFor(%v): want %d, have %d", long, want, n)
+		}
+
+		if d := sf.DurationOf(int(want)); d != long {
+			t.Errorf("DurationOf(%d): want %v, have %v", want, long, d)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Stream is a source of interleaved floating point samples in the
+// nominal range [-1, 1].
+type Stream interface {
+	// Channels returns the number of interleaved channels.
+	Channels() int
+
+	// ReadSamples reads up to len(p) samples into p and returns the number
+	// of samples read. This is always a multiple of Channels(). As with
+	// io.Reader, n may be non-zero when an error is returned. Returns
+	// io.EOF once the stream is exhausted.
+	ReadSamples(p []float32) (n int, err error)
+}
+
+// pcmStream decodes PCM data from a reader.
+type pcmStream struct {
+	r        io.Reader
+	bits     int
+	big      bool
+	channels int
+	buf      []byte
+	n        int // Number of bytes held in buf.
+}
+
+// NewPCMStream returns a Stream which decodes PCM data in the given
+// sample format from r.
+func NewPCMStream(r io.Reader, sf *SampleFormat) Stream {
+	return &pcmStream{
+		r:        r,
+		bits:     sf.Bits,
+		big:      sf.ByteOrder.isBig(),
+		channels: sf.Channels,
+	}
+}
+
+func (s *pcmStream) Channels() int {
+	return s.channels
+}
+
+func (s *pcmStream) ReadSamples(p []float32) (int, error) {
+	size := sampleSize(s.bits)
+	frame := size * s.channels
+	if frame <= 0 {
+		return 0, io.EOF
+	}
+
+	need := len(p) / s.channels * frame
+	if need == 0 {
+		return 0, nil
+	}
+
+	if len(s.buf) < need {
+		buf := make([]byte, need)
+		copy(buf, s.buf[:s.n])
+		s.buf = buf
+	}
+
+	var err error
+	if s.n < need {
+		var m int
+		m, err = s.r.Read(s.buf[s.n:need])
+		s.n += m
+	}
+
+	// Decode whole frames and keep any trailing partial frame around
+	// for the next call.
+	whole := s.n / frame * frame
+	n := whole / size
+
+	for i := 0; i < n; i++ {
+		v := getSample(s.buf[i*size:], s.bits, s.big)
+		p[i] = float32(float64(v) / (1 << 31))
+	}
+
+	s.n = copy(s.buf, s.buf[whole:s.n])
+	return n, err
+}
+
+// PlayStream reads samples from s and plays them on the device until s
+// is exhausted or ctx is done. The samples are converted to the device's
+// sample format. The stream must have the same number of channels as the
+// device.
+//
+// Returns nil once s reports io.EOF.
+func (d *Device) PlayStream(ctx context.Context, s Stream) error {
+	if s.Channels() != d.format.Channels {
+		return fmt.Errorf("stream has %d channels, device has %d: %w",
+			s.Channels(), d.format.Channels, ErrBadFormat)
+	}
+
+	size := d.format.FrameSize()
+	if size <= 0 {
+		return ErrBadFormat
+	}
+
+	buf := make([]float32, d.chunkSize()/size*s.Channels())
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := s.ReadSamples(buf)
+		if n > 0 {
+			if perr := d.PlayFloat32(buf[:n]); perr != nil {
+				return perr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
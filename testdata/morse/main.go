@@ -46,14 +46,15 @@ func main() {
 
 	// Get the requested- or system's default audio driver.
 	var id int
+	var err error
 	if len(cfg.Driver) == 0 {
-		id = ao.DefaultDriver()
+		id, err = ao.DefaultDriver()
 	} else {
-		id = ao.DriverByName(cfg.Driver)
+		id, err = ao.DriverByName(cfg.Driver)
 	}
 
-	if id == -1 {
-		fmt.Fprintln(os.Stderr, "no valid audio driver found")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "no valid audio driver found:", err)
 		ao.Shutdown()
 		os.Exit(1)
 	}
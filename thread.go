@@ -0,0 +1,108 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Several sound systems used by libao keep thread specific state and
+// break when they are used from different threads. Go moves goroutines
+// between threads at will, so all calls into libao are funnelled through
+// an executor: a goroutine which is locked to a single OS thread.
+//
+// The executor runs one call at a time. This serializes ao_play() across
+// all devices: while one device blocks in playback, every other call into
+// libao waits for it.
+
+// executor runs functions on a single OS thread.
+type executor struct {
+	calls chan func()
+	once  sync.Once
+}
+
+// exec is the executor used for all calls into libao.
+var exec = newExecutor()
+
+func newExecutor() *executor {
+	return &executor{calls: make(chan func())}
+}
+
+// start starts serving calls on a new goroutine, locked to its own
+// OS thread, unless the executor is already running.
+func (e *executor) start() {
+	e.once.Do(e.spawn)
+}
+
+// spawn serves calls on a new goroutine, locked to its own OS thread.
+func (e *executor) spawn() {
+	go func() {
+		runtime.LockOSThread()
+		for fn := range e.calls {
+			fn()
+		}
+	}()
+}
+
+// do runs fn on the executor's thread and waits for it to return.
+// fn must not call do itself.
+func (e *executor) do(fn func()) {
+	e.start()
+
+	done := make(chan struct{})
+	e.calls <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
+
+// run serves calls on the current thread until f returns.
+// It panics if the executor is already running.
+func (e *executor) run(f func()) {
+	claimed := false
+	e.once.Do(func() { claimed = true })
+
+	if !claimed {
+		panic("ao: Main called after the library was used")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	for {
+		select {
+		case fn := <-e.calls:
+			fn()
+		case <-done:
+			// Do not leave late callers hanging.
+			e.spawn()
+			return
+		}
+	}
+}
+
+// Main hands the program's main thread over to the library. It runs f
+// in a separate goroutine and executes all calls into libao on the main
+// thread until f returns. Programs using sound systems which must be
+// driven from the main thread (see Init) should call it from main():
+//
+//	func main() {
+//		ao.Main(run)
+//	}
+//
+// Without Main, libao is driven from a dedicated thread of its own.
+// Either way, all calls into libao are made from one and the same thread.
+// Main must be called before any other function in this package; it
+// panics otherwise. Calls made after f returns are served by a new thread.
+//
+// Builds without cgo have no libao to drive, so the main goroutine is not
+// tied to the main thread there.
+func Main(f func()) {
+	exec.run(f)
+}
@@ -0,0 +1,67 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestExecutor(t *testing.T) {
+	e := newExecutor()
+
+	var wg sync.WaitGroup
+	var running, total int
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.do(func() {
+					running++
+					if running > 1 {
+						t.Error("calls are not serialized")
+					}
+					total++
+					running--
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if total != 800 {
+		t.Fatalf("have %d calls, want 800", total)
+	}
+}
+
+func TestExecutorRun(t *testing.T) {
+	e := newExecutor()
+
+	var served bool
+	e.run(func() {
+		e.do(func() { served = true })
+	})
+
+	if !served {
+		t.Fatal("call was not served")
+	}
+
+	// Calls made after run returns must still be served.
+	e.do(func() { served = false })
+
+	if served {
+		t.Fatal("call was not served after run returned")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("second run did not panic")
+		}
+	}()
+
+	e.run(func() {})
+}
@@ -0,0 +1,234 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WAV format tags.
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatExtensible = 0xfffe
+)
+
+// wavSubFormatPCM is the KSDATAFORMAT_SUBTYPE_PCM GUID.
+var wavSubFormatPCM = []byte{
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00,
+	0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71,
+}
+
+// wavSpeakers maps channels to their WAVE_FORMAT_EXTENSIBLE speaker
+// position bits.
+var wavSpeakers = map[Channel]uint32{
+	ChannelL:   0x001,
+	ChannelR:   0x002,
+	ChannelC:   0x004,
+	ChannelM:   0x004,
+	ChannelLFE: 0x008,
+	ChannelBL:  0x010,
+	ChannelBR:  0x020,
+	ChannelCL:  0x040,
+	ChannelCR:  0x080,
+	ChannelBC:  0x100,
+	ChannelSL:  0x200,
+	ChannelSR:  0x400,
+}
+
+// NewWAVEncoder creates an encoder which writes a RIFF WAVE file.
+// WAV files hold little-endian samples; 8-bit samples are unsigned.
+//
+// The WAVE_FORMAT_EXTENSIBLE header is used for more than two channels,
+// more than 16 bits per sample, or when the sample format's matrix is
+// not the default for its channel count. Its channel mask is derived
+// from the matrix. WAV requires channels to be stored in the order of
+// their mask bits, so the channels of each frame are reordered to match.
+// Channels without a speaker position (X, A1-A4) follow the others, in
+// their original order.
+//
+// Returns an error wrapping ErrBadFormat if two channels in the matrix
+// share a speaker position; e.g.: M and C.
+func NewWAVEncoder(w io.Writer, sf *SampleFormat) (*Encoder, error) {
+	if err := sf.Validate(); err != nil {
+		return nil, err
+	}
+
+	mask, order, err := wavLayout(sf)
+	if err != nil {
+		return nil, err
+	}
+
+	target := *sf
+	target.ByteOrder = EndianLittle
+
+	extensible := sf.Channels > 2 || sf.Bits > 16 ||
+		(len(sf.Matrix) > 0 && mask != wavDefaultMask(sf.Channels))
+
+	e, err := newEncoder(w, sf, &target, true, true, func(size int64) []byte {
+		return wavHeader(sf, size, extensible, mask)
+	})
+
+	if err == nil && order != nil {
+		e.conv = &reorderWriter{
+			w:     e.conv,
+			order: order,
+			size:  sampleSize(sf.Bits),
+		}
+	}
+
+	return e, err
+}
+
+// wavHeader builds the RIFF, fmt and data chunk headers for data
+// of the given size. A negative size denotes an unknown size.
+func wavHeader(sf *SampleFormat, size int64, extensible bool, mask uint32) []byte {
+	fmtSize := 16
+	if extensible {
+		fmtSize = 40
+	}
+
+	h := make([]byte, 12+8+fmtSize+8)
+	le := binary.LittleEndian
+	block := sf.FrameSize()
+
+	riff := int64(-1)
+	if size >= 0 {
+		riff = int64(len(h)) - 8 + size + size%2
+	}
+
+	copy(h[0:], "RIFF")
+	le.PutUint32(h[4:], headerSize(riff))
+	copy(h[8:], "WAVE")
+
+	copy(h[12:], "fmt ")
+	le.PutUint32(h[16:], uint32(fmtSize))
+
+	f := h[20:]
+	le.PutUint16(f[0:], wavFormatPCM)
+	le.PutUint16(f[2:], uint16(sf.Channels))
+	le.PutUint32(f[4:], uint32(sf.Rate))
+	le.PutUint32(f[8:], uint32(sf.Rate*block))
+	le.PutUint16(f[12:], uint16(block))
+	le.PutUint16(f[14:], uint16(sampleSize(sf.Bits)*8))
+
+	if extensible {
+		le.PutUint16(f[0:], wavFormatExtensible)
+		le.PutUint16(f[16:], 22)
+		le.PutUint16(f[18:], uint16(sf.Bits))
+		le.PutUint32(f[20:], mask)
+		copy(f[24:], wavSubFormatPCM)
+	}
+
+	d := h[20+fmtSize:]
+	copy(d[0:], "data")
+	le.PutUint32(d[4:], headerSize(size))
+	return h
+}
+
+// wavLayout derives a speaker position mask from the sample format's
+// matrix, along with the order in which its channels must be stored.
+// order[i] holds the matrix index of the i'th channel to store; it is
+// nil if the matrix is already in the right order. The mask is 0 if
+// there is no matrix.
+func wavLayout(sf *SampleFormat) (mask uint32, order []int, err error) {
+	m, err := ParseMatrix(sf.Matrix)
+	if err != nil || len(m) == 0 {
+		return 0, nil, err
+	}
+
+	type speaker struct {
+		bit   uint32
+		index int
+	}
+
+	var placed []speaker
+	var rest []int
+
+	for i, c := range m {
+		bit, ok := wavSpeakers[c]
+		if !ok {
+			rest = append(rest, i)
+			continue
+		}
+
+		if mask&bit != 0 {
+			return 0, nil, fmt.Errorf("matrix %q: channel %v shares a WAV speaker position: %w",
+				sf.Matrix, c, ErrBadFormat)
+		}
+
+		mask |= bit
+		placed = append(placed, speaker{bit, i})
+	}
+
+	sort.Slice(placed, func(i, j int) bool {
+		return placed[i].bit < placed[j].bit
+	})
+
+	order = make([]int, 0, len(m))
+	for _, s := range placed {
+		order = append(order, s.index)
+	}
+	order = append(order, rest...)
+
+	for i, v := range order {
+		if i != v {
+			return mask, order, nil
+		}
+	}
+
+	return mask, nil, nil
+}
+
+// reorderWriter reorders the channels of each frame written to it.
+// Trailing bytes which do not make up a whole frame are kept until
+// the next write.
+type reorderWriter struct {
+	w       io.Writer
+	order   []int  // Source channel of each output channel.
+	size    int    // Bytes per sample.
+	partial []byte // Incomplete frame left over from the last write.
+	buf     []byte
+	tmp     []byte
+}
+
+func (rw *reorderWriter) Write(p []byte) (int, error) {
+	frame := rw.size * len(rw.order)
+
+	rw.buf = append(append(rw.buf[:0], rw.partial...), p...)
+	whole := len(rw.buf) - len(rw.buf)%frame
+	rw.partial = append(rw.partial[:0], rw.buf[whole:]...)
+
+	if len(rw.tmp) < frame {
+		rw.tmp = make([]byte, frame)
+	}
+
+	for f := 0; f < whole; f += frame {
+		copy(rw.tmp, rw.buf[f:f+frame])
+
+		for i, src := range rw.order {
+			copy(rw.buf[f+i*rw.size:], rw.tmp[src*rw.size:(src+1)*rw.size])
+		}
+	}
+
+	if _, err := rw.w.Write(rw.buf[:whole]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// wavDefaultMask returns the speaker positions implied by a plain
+// WAVE_FORMAT_PCM header with the given number of channels.
+func wavDefaultMask(channels int) uint32 {
+	switch channels {
+	case 1:
+		return wavSpeakers[ChannelC]
+	case 2:
+		return wavSpeakers[ChannelL] | wavSpeakers[ChannelR]
+	}
+	return 0
+}
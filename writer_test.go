@@ -0,0 +1,232 @@
+// This file is subject to a BSD license.
+// Its contents can be found in the enclosed LICENSE file.
+
+package ao
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	osexec "os/exec"
+	"testing"
+	"time"
+)
+
+func TestOpenWriter(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName("wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf := *format
+	sf.ByteOrder = EndianLittle
+
+	var buf bytes.Buffer
+	dev, err := OpenWriter(driver, &buf, &sf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dev.IsLive() || len(dev.Filename()) > 0 {
+		t.Fatalf("device claims to be live or have a file")
+	}
+
+	data := make([]byte, 64*1024)
+	rand.Read(data)
+
+	if err = dev.Play(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = dev.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(buf.Bytes(), data) {
+		t.Fatalf("output does not hold the data played; have %d bytes", buf.Len())
+	}
+}
+
+func TestOpenWriterErrors(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	live, err := DriverByName(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err = OpenWriter(live, &buf, format, nil); !errors.Is(err, ErrNotFile) {
+		t.Fatalf("live driver: have %v, want %v", err, ErrNotFile)
+	}
+
+	driver, err := DriverByName("raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &failWriter{limit: 1024}
+	dev, err := OpenWriter(driver, w, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The error may surface on any write after the limit,
+	// or when the device is closed.
+	data := make([]byte, 4096)
+	for i := 0; i < 8 && err == nil; i++ {
+		err = dev.Play(data)
+	}
+
+	if cerr := dev.Close(); err == nil {
+		err = cerr
+	}
+
+	if !errors.Is(err, errWriteFailed) {
+		t.Fatalf("have %v, want %v", err, errWriteFailed)
+	}
+}
+
+func TestOpenWriterSlow(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName("raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if driver >= goDriverBase {
+		t.Skip("Go drivers write to w directly")
+	}
+
+	w := &gateWriter{open: make(chan struct{})}
+	dev, err := OpenWriter(driver, w, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Well over the capacity of a pipe.
+	data := make([]byte, 256*1024)
+	rand.Read(data)
+
+	played := make(chan error, 1)
+	go func() { played <- dev.Play(data) }()
+
+	select {
+	case err = <-played:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		close(w.open)
+		t.Fatal("write blocked on a slow writer")
+	}
+
+	// Other calls into libao must not be held up either.
+	other := openNull(t)
+	if err = other.Play(make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+
+	close(w.open)
+	if err = dev.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(w.buf.Bytes(), data) {
+		t.Fatalf("output does not hold the data played; have %d bytes", w.buf.Len())
+	}
+}
+
+func TestOpenWriterChild(t *testing.T) {
+	Init()
+	defer Shutdown()
+
+	driver, err := DriverByName("raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if driver >= goDriverBase {
+		t.Skip("Go drivers write to w directly")
+	}
+
+	sleep, err := osexec.LookPath("sleep")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var buf bytes.Buffer
+	dev, err := OpenWriter(driver, &buf, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 64*1024)
+	rand.Read(data)
+
+	if err = dev.Play(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// The child inherits libao's descriptor for the pipe.
+	child := osexec.Command(sleep, "10")
+	if err = child.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer child.Wait()
+	defer child.Process.Kill()
+
+	closed := make(chan error, 1)
+	go func() { closed <- dev.Close() }()
+
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		child.Process.Kill()
+		t.Fatal("close waits for a child process holding the pipe")
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("output does not hold the data played; have %d bytes", buf.Len())
+	}
+}
+
+// gateWriter blocks all writes until open is closed.
+type gateWriter struct {
+	open chan struct{}
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.open
+	return w.buf.Write(p)
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failWriter fails once more than limit bytes have been written.
+type failWriter struct {
+	limit int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errWriteFailed
+	}
+
+	w.limit -= len(p)
+	return len(p), nil
+}